@@ -0,0 +1,491 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// reasonRoomClosed es el motivo de los jugadores cuya sala cerró un administrador. Se
+// informa como status room_closed en lugar de cancelled.
+const reasonRoomClosed = "room_closed"
+
+// cancelNotice es el aviso pendiente para un jugador retirado de la cola.
+type cancelNotice struct {
+	Reason string
+	At     time.Time
+}
+
+// cancelNoticeTTL es el tiempo que se conserva un aviso que nadie ha consultado.
+const cancelNoticeTTL = 5 * time.Minute
+
+// requireAdminKey rechaza con 401 las peticiones cuya X-Admin-Key no es adminKey. Si
+// adminKey está vacía, las rechaza todas.
+func requireAdminKey(adminKey string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-Admin-Key")
+		if adminKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requireAdminToken rechaza con 401 las peticiones sin "Authorization: Bearer <adminToken>".
+// Si adminToken está vacío, las rechaza todas.
+func requireAdminToken(adminToken string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// takeCancelReason consume el aviso de retirada de playerID y devuelve su motivo, o
+// "cancelled" si no hay aviso. Se usa cuando se cierra Player.Cancelled.
+func takeCancelReason(playerID string) string {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	notice, ok := state.cancelNotices[playerID]
+	if !ok {
+		return "cancelled"
+	}
+	delete(state.cancelNotices, playerID)
+	return notice.Reason
+}
+
+// noticeStatus devuelve el status con el que se informa un aviso de retirada.
+func noticeStatus(reason string) string {
+	if reason == reasonRoomClosed {
+		return "room_closed"
+	}
+	return "cancelled"
+}
+
+// registerPprof expone los perfiles de runtime bajo /debug/pprof/ protegidos por X-Admin-Key.
+// go tool pprof no permite enviar cabeceras, así que el uso típico es descargar el perfil
+// y analizarlo después:
+//
+//	curl -H "X-Admin-Key: $ADMIN_KEY" -o heap.pprof http://localhost:8080/debug/pprof/heap
+//	go tool pprof heap.pprof
+func registerPprof(mux *http.ServeMux, adminKey string) {
+	mux.HandleFunc("/debug/pprof/", requireAdminKey(adminKey, pprof.Index))
+	mux.HandleFunc("/debug/pprof/cmdline", requireAdminKey(adminKey, pprof.Cmdline))
+	mux.HandleFunc("/debug/pprof/profile", requireAdminKey(adminKey, pprof.Profile))
+	mux.HandleFunc("/debug/pprof/symbol", requireAdminKey(adminKey, pprof.Symbol))
+	mux.HandleFunc("/debug/pprof/trace", requireAdminKey(adminKey, pprof.Trace))
+}
+
+// handlePoolFlush atiende POST /admin/pool/flush?reason=... y retira de la cola a
+// todos los jugadores en espera. Los jugadores ya emparejados no se tocan.
+func handlePoolFlush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reason := r.URL.Query().Get("reason")
+	if reason == "" {
+		reason = "server_maintenance"
+	}
+
+	flushed := flushPool(reason)
+	slog.InfoContext(r.Context(), "pool flushed", "flushed", flushed, "reason", reason)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"flushed": flushed})
+}
+
+// expireCancelNotices descarta los avisos más antiguos que cancelNoticeTTL.
+// Requiere state.mu.
+func expireCancelNotices() {
+	for id, n := range state.cancelNotices {
+		if time.Since(n.At) > cancelNoticeTTL {
+			delete(state.cancelNotices, id)
+		}
+	}
+}
+
+// draining se activa con /admin/pool/drain: no entran jugadores nuevos, pero los que ya
+// están en cola se siguen emparejando. Sirve para vaciar una instancia antes de pararla.
+var draining atomic.Bool
+
+// checkDraining responde 503 si el servidor no admite jugadores nuevos y devuelve false
+// en ese caso.
+func checkDraining(w http.ResponseWriter) bool {
+	if draining.Load() {
+		http.Error(w, "Server is draining", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// handlePoolDrain atiende POST /admin/pool/drain?enabled=true|false. Por defecto activa
+// el drenado; con enabled=false vuelve a admitir jugadores.
+func handlePoolDrain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	enabled := true
+	if v := r.URL.Query().Get("enabled"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Invalid enabled", http.StatusBadRequest)
+			return
+		}
+		enabled = b
+	}
+	draining.Store(enabled)
+
+	state.mu.RLock()
+	waiting := len(state.pool)
+	state.mu.RUnlock()
+	slog.InfoContext(r.Context(), "pool drain changed", "draining", enabled, "waiting", waiting)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"draining": enabled, "waiting": waiting})
+}
+
+// adminPlayer es un jugador en la respuesta de /admin/players.
+type adminPlayer struct {
+	PlayerID    string  `json:"playerID"`
+	Status      string  `json:"status"`
+	Platform    string  `json:"platform,omitempty"`
+	ELO         int     `json:"elo"`
+	WaitSeconds float64 `json:"waitSeconds"`
+	RoomID      string  `json:"roomID,omitempty"`
+}
+
+// handleListPlayers atiende GET /admin/players con todos los jugadores que conoce el
+// servidor: en cola (waiting o away), anfitriones de salas privadas (private) y
+// emparejados (matched), ordenados por ID.
+func handleListPlayers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	now := time.Now()
+	state.mu.RLock()
+	players := make([]adminPlayer, 0, len(state.players))
+	for _, p := range state.players {
+		status := "waiting"
+		switch {
+		case p.Matched:
+			status = "matched"
+		case p.Private:
+			status = "private"
+		case p.IsAway:
+			status = "away"
+		}
+		players = append(players, adminPlayer{
+			PlayerID:    p.ID,
+			Status:      status,
+			Platform:    p.Platform,
+			ELO:         p.ELO,
+			WaitSeconds: now.Sub(p.CreatedAt).Seconds(),
+			RoomID:      p.RoomID,
+		})
+	}
+	state.mu.RUnlock()
+	slices.SortFunc(players, func(a, b adminPlayer) int { return strings.Compare(a.PlayerID, b.PlayerID) })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(players)
+}
+
+// handleListRooms atiende GET /admin/rooms con todas las salas, de la más antigua a la
+// más reciente.
+func handleListRooms(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state.mu.RLock()
+	rooms := make([]*Room, 0, len(state.rooms))
+	for _, room := range state.rooms {
+		rooms = append(rooms, room)
+	}
+	slices.SortFunc(rooms, func(a, b *Room) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	// Codificamos bajo el lock porque las salas cambian de estado; la escritura va fuera
+	body, err := json.Marshal(rooms)
+	state.mu.RUnlock()
+	if err != nil {
+		http.Error(w, "Could not encode rooms", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(body, '\n'))
+}
+
+// handleAdminPlayers enruta /admin/players/{id}/...
+func handleAdminPlayers(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/admin/players/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID, action := parts[0], parts[1]
+	switch action {
+	case "anonymize":
+		handleAnonymize(w, r, playerID)
+	case "blocks":
+		handleBlocks(w, r, playerID)
+	case "ban":
+		handleBan(w, r, playerID)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// anonymousID es el identificador que sustituye a playerID tras un borrado. Es estable
+// para que todas las apariciones del mismo jugador sigan siendo coherentes entre sí.
+func anonymousID(playerID string) string {
+	sum := sha256.Sum256([]byte(playerID))
+	return "deleted_user_" + hex.EncodeToString(sum[:6])
+}
+
+// handleAnonymize atiende POST /admin/players/{id}/anonymize (derecho de supresión).
+// Saca al jugador de la cola, sustituye su ID en salas, torneos, historial,
+// clasificación, suscripciones, capturas y emparejamientos de sus rivales, y borra sus
+// bloqueos. El historial se actualiza primero: si falla, no se toca nada más y la
+// petición puede repetirse.
+func handleAnonymize(w http.ResponseWriter, r *http.Request, playerID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	anonID := anonymousID(playerID)
+
+	if err := anonymizeHistory(r.Context(), playerID, anonID); err != nil {
+		http.Error(w, "Could not anonymize match history", http.StatusInternalServerError)
+		return
+	}
+
+	state.mu.Lock()
+	removePlayer(playerID)
+	delete(state.cancelNotices, playerID)
+	delete(state.reconnecting, playerID)
+	delete(state.blocks, playerID)
+	delete(state.bans, playerID)
+	for id, blocks := range state.blocks {
+		if slices.Contains(blocks, playerID) {
+			state.blocks[id] = slices.DeleteFunc(slices.Clone(blocks), func(b string) bool { return b == playerID })
+		}
+	}
+	roomsScrubbed := 0
+	for _, room := range state.rooms {
+		if !slices.Contains(room.Players, playerID) {
+			continue
+		}
+		// Copiamos en lugar de modificar: /stats puede estar leyendo el slice anterior
+		room.Players = replaceID(room.Players, playerID, anonID)
+		rolls := slices.Clone(room.Rolls)
+		for k := range rolls {
+			if rolls[k].PlayerID == playerID {
+				rolls[k].PlayerID = anonID
+			}
+			if rolls[k].WaitingFor == playerID {
+				rolls[k].WaitingFor = anonID
+			}
+		}
+		room.Rolls = rolls
+		// El chat se copia al leerlo, así que puede modificarse en sitio
+		for k := range room.chat.messages {
+			if room.chat.messages[k].PlayerID == playerID {
+				room.chat.messages[k].PlayerID = anonID
+			}
+		}
+		roomsScrubbed++
+	}
+	for _, pr := range state.privateRooms {
+		if pr.HostID == playerID {
+			pr.HostID = anonID
+		}
+	}
+	anonymizeOpponents(playerID, anonID)
+	anonymizeTournaments(playerID, anonID)
+	state.mu.Unlock()
+
+	emailMutex.Lock()
+	delete(emailOptIns, playerID)
+	emailMutex.Unlock()
+
+	leaderboard.rename(playerID, anonID)
+
+	queueSnapshotsMutex.Lock()
+	for i, s := range queueSnapshots {
+		if !slices.ContainsFunc(s.Entries, func(e QueueEntry) bool { return e.PlayerID == playerID }) {
+			continue
+		}
+		// Copiamos en lugar de modificar: /admin/queue-snapshots codifica las entradas
+		// después de soltar el lock
+		entries := slices.Clone(s.Entries)
+		for k := range entries {
+			if entries[k].PlayerID == playerID {
+				entries[k].PlayerID = anonID
+			}
+		}
+		queueSnapshots[i].Entries = entries
+	}
+	queueSnapshotsMutex.Unlock()
+
+	// No registramos el ID original: el log también debe quedar anonimizado
+	slog.InfoContext(r.Context(), "player anonymized", "player_id", anonID, "rooms_scrubbed", roomsScrubbed)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"anonymizedID": anonID})
+}
+
+// anonymizeOpponents sustituye playerID en lo que guardan sus rivales: el
+// emparejamiento aún sin entregar y los datos de /reconnect. El nombre y el avatar del
+// rival se borran. Requiere state.mu.
+func anonymizeOpponents(playerID, anonID string) {
+	for _, p := range state.players {
+		if !p.Matched {
+			continue
+		}
+		// Solo tocamos el emparejamiento si lo sacamos del canal: si otro ya lo ha
+		// recibido, está leyendo estos campos sin el lock
+		select {
+		case ids := <-p.OpponentIDs:
+			if slices.Contains(ids, playerID) {
+				ids = replaceID(ids, playerID, anonID)
+				p.OpponentName, p.OpponentAvatar = "", ""
+			}
+			p.OpponentIDs <- ids
+		default:
+		}
+	}
+
+	for _, p := range state.reconnecting {
+		data := p.ReconnectData
+		if data == nil || !slices.Contains(data.Players, playerID) {
+			continue
+		}
+		// handleReconnect copia ReconnectData bajo el lock pero codifica Players fuera
+		// de él: sustituimos en lugar de modificar
+		p.ReconnectData = &ReconnectData{
+			Players:      replaceID(data.Players, playerID, anonID),
+			RoomID:       data.RoomID,
+			MatchQuality: data.MatchQuality,
+		}
+	}
+}
+
+// replaceID devuelve una copia de ids con oldID sustituido por newID.
+func replaceID(ids []string, oldID, newID string) []string {
+	replaced := slices.Clone(ids)
+	for k, id := range replaced {
+		if id == oldID {
+			replaced[k] = newID
+		}
+	}
+	return replaced
+}
+
+// handleDeletePlayer atiende DELETE /admin/player/{id}: saca al jugador de la cola y
+// del players map. Si estaba en cola, su stream recibe cancelled con removed_by_admin.
+func handleDeletePlayer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	playerID := r.URL.Path[len("/admin/player/"):]
+	if playerID == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	state.mu.Lock()
+	player, exists := state.players[playerID]
+	_, reconnecting := state.reconnecting[playerID]
+	if exists && !player.Matched {
+		state.cancelNotices[playerID] = cancelNotice{Reason: "removed_by_admin", At: time.Now()}
+	}
+	removePlayer(playerID)
+	delete(state.reconnecting, playerID)
+	state.mu.Unlock()
+
+	if !exists && !reconnecting {
+		http.Error(w, "Player not found", http.StatusNotFound)
+		return
+	}
+	slog.InfoContext(r.Context(), "player removed by admin", "player_id", playerID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"removed": true})
+}
+
+// handleDeleteRoom atiende DELETE /admin/room/{id}: cierra la sala y avisa con
+// room_closed a los jugadores que aún no habían recogido su emparejamiento.
+func handleDeleteRoom(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	roomID := r.URL.Path[len("/admin/room/"):]
+	if roomID == "" {
+		http.Error(w, "Room ID is required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	state.mu.Lock()
+	room, exists := state.rooms[roomID]
+	if exists {
+		room.transition(RoomExpired, now)
+		delete(state.rooms, roomID)
+		for _, id := range room.Players {
+			delete(state.reconnecting, id)
+
+			p, ok := state.players[id]
+			if !ok || p.RoomID != roomID {
+				continue
+			}
+			// El emparejamiento pendiente ya no vale: lo descartamos y cerramos Cancelled
+			// para que /status, SSE y websocket informen room_closed
+			select {
+			case <-p.OpponentIDs:
+			default:
+			}
+			state.cancelNotices[id] = cancelNotice{Reason: reasonRoomClosed, At: now}
+			close(p.Cancelled)
+			delete(state.players, id)
+		}
+	}
+	state.mu.Unlock()
+
+	if !exists {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+	slog.InfoContext(r.Context(), "room closed by admin", "room_id", roomID)
+	recordMatchEnded(roomID, now)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"removed": true})
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func anonymize(playerID string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/admin/players/"+playerID+"/anonymize", nil)
+	handleAdminPlayers(w, r)
+	return w
+}
+
+func TestAnonymizeScrubsOpponents(t *testing.T) {
+	resetState(t)
+	anonID := anonymousID("alice")
+	now := time.Now()
+
+	room := newRoom("room1", []string{"alice", "bob"}, now)
+	state.rooms[room.ID] = room
+	recordMatchCreated(room.ID, room.Players, now)
+
+	// bob aún no ha recogido el emparejamiento; carol ya lo recogió y está en reconnecting
+	bob := newPlayer("bob", "", "Bob", "", defaultELO)
+	bob.Matched, bob.RoomID = true, room.ID
+	bob.OpponentName, bob.OpponentAvatar = "Alice", "https://example.com/alice.png"
+	bob.OpponentIDs <- []string{"alice"}
+	state.players["bob"] = bob
+
+	carol := newPlayer("carol", "", "", "", defaultELO)
+	carol.ReconnectDeadline = now.Add(reconnectWindow)
+	carol.ReconnectData = &ReconnectData{Players: []string{"alice"}, RoomID: "room2", OpponentName: "Alice"}
+	state.reconnecting["carol"] = carol
+
+	leaderboard.record("alice", []string{"bob"})
+
+	if w := anonymize("alice"); w.Code != http.StatusOK {
+		t.Fatalf("anonymize: status %d: %s", w.Code, w.Body)
+	}
+
+	if got := room.Players; !slices.Equal(got, []string{anonID, "bob"}) {
+		t.Errorf("room players = %v", got)
+	}
+	if got := <-bob.OpponentIDs; !slices.Equal(got, []string{anonID}) {
+		t.Errorf("bob pending match = %v", got)
+	}
+	if bob.OpponentName != "" || bob.OpponentAvatar != "" {
+		t.Errorf("bob still has opponent profile %q %q", bob.OpponentName, bob.OpponentAvatar)
+	}
+	data := state.reconnecting["carol"].ReconnectData
+	if !slices.Equal(data.Players, []string{anonID}) || data.OpponentName != "" || data.RoomID != "room2" {
+		t.Errorf("carol reconnect data = %+v", data)
+	}
+	for _, e := range leaderboard.top(10) {
+		if e.PlayerID == "alice" {
+			t.Error("leaderboard still has alice")
+		}
+	}
+
+	var player1 string
+	if err := db.QueryRow(`SELECT player1_id FROM matches WHERE room_id = ?`, room.ID).Scan(&player1); err != nil {
+		t.Fatal(err)
+	}
+	if player1 != anonID {
+		t.Errorf("history player1 = %q, want %q", player1, anonID)
+	}
+}
+
+func TestAnonymizeHistoryFailureLeavesStateUntouched(t *testing.T) {
+	resetState(t)
+
+	room := newRoom("room1", []string{"alice", "bob"}, time.Now())
+	state.rooms[room.ID] = room
+	leaderboard.record("alice", []string{"bob"})
+
+	db.Close()
+	if w := anonymize("alice"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("anonymize with closed history: status %d, want 500", w.Code)
+	}
+
+	if !slices.Equal(room.Players, []string{"alice", "bob"}) {
+		t.Errorf("room players changed: %v", room.Players)
+	}
+	if top := leaderboard.top(10); len(top) == 0 || top[0].PlayerID != "alice" {
+		t.Errorf("leaderboard changed: %+v", top)
+	}
+}
+
+func TestAnonymizeConcurrentWithQueueSnapshots(t *testing.T) {
+	resetState(t)
+
+	queueSnapshotsMutex.Lock()
+	for i := 0; i < 50; i++ {
+		queueSnapshots = append(queueSnapshots, QueueSnapshot{
+			Timestamp: time.Now(),
+			PoolSize:  2,
+			Entries:   []QueueEntry{{PlayerID: "alice"}, {PlayerID: "bob"}},
+		})
+	}
+	queueSnapshotsMutex.Unlock()
+
+	// Con -race, esto detecta que anonymize escriba en Entries mientras se codifican
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		anonymize("alice")
+	}()
+	go func() {
+		defer wg.Done()
+		w := httptest.NewRecorder()
+		handleQueueSnapshots(w, httptest.NewRequest(http.MethodGet, "/admin/queue-snapshots", nil))
+	}()
+	wg.Wait()
+
+	w := httptest.NewRecorder()
+	handleQueueSnapshots(w, httptest.NewRequest(http.MethodGet, "/admin/queue-snapshots", nil))
+	if strings.Contains(w.Body.String(), `"alice"`) {
+		t.Error("snapshots still contain alice")
+	}
+}
+
+func TestBanRemovesAndBlocksJoin(t *testing.T) {
+	resetState(t)
+	cfg := defaultConfig()
+
+	join(&cfg, "id=p1", "192.0.2.1:1234")
+	w := httptest.NewRecorder()
+	handleAdminPlayers(w, httptest.NewRequest(http.MethodPost, "/admin/players/p1/ban?duration=1h", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("ban: status %d: %s", w.Code, w.Body)
+	}
+	if len(state.pool) != 0 || state.cancelNotices["p1"].Reason != "banned" {
+		t.Errorf("banned player still queued or without notice: pool=%d notice=%+v", len(state.pool), state.cancelNotices["p1"])
+	}
+	if w := join(&cfg, "id=p1", "192.0.2.1:1234"); w.Code != http.StatusForbidden {
+		t.Errorf("join while banned: status %d, want 403", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handleAdminPlayers(w, httptest.NewRequest(http.MethodDelete, "/admin/players/p1/ban", nil))
+	if w := join(&cfg, "id=p1", "192.0.2.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("join after unban: status %d, want 200", w.Code)
+	}
+}
+
+func TestDrainRejectsNewPlayers(t *testing.T) {
+	resetState(t)
+	cfg := defaultConfig()
+	t.Cleanup(func() { draining.Store(false) })
+
+	join(&cfg, "id=p1", "192.0.2.1:1234")
+	w := httptest.NewRecorder()
+	handlePoolDrain(w, httptest.NewRequest(http.MethodPost, "/admin/pool/drain", nil))
+	if !strings.Contains(w.Body.String(), `"waiting":1`) {
+		t.Errorf("drain response = %s", w.Body)
+	}
+	if w := join(&cfg, "id=p2", "192.0.2.1:1234"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("join while draining: status %d, want 503", w.Code)
+	}
+
+	matcherRunning.Store(true)
+	t.Cleanup(func() { matcherRunning.Store(false) })
+	w = httptest.NewRecorder()
+	handleReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil), &cfg)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("readyz while draining: status %d, want 503", w.Code)
+	}
+
+	handlePoolDrain(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/admin/pool/drain?enabled=false", nil))
+	if w := join(&cfg, "id=p2", "192.0.2.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("join after resume: status %d, want 200", w.Code)
+	}
+}
+
+func TestAnnouncement(t *testing.T) {
+	t.Cleanup(func() { announcement = nil })
+
+	w := httptest.NewRecorder()
+	handleAnnounce(w, httptest.NewRequest(http.MethodPost, "/admin/announce", strings.NewReader(`{"message":"maintenance in 5m","severity":"warning"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("announce: status %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	handleAnnouncement(w, httptest.NewRequest(http.MethodGet, "/announcement", nil))
+	if !strings.Contains(w.Body.String(), `"severity":"warning"`) {
+		t.Errorf("announcement = %s", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	handleAnnounce(w, httptest.NewRequest(http.MethodPost, "/admin/announce", strings.NewReader(`{"message":"x","severity":"loud"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("announce with bad severity: status %d, want 400", w.Code)
+	}
+
+	handleAnnounce(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/admin/announce", strings.NewReader(`{"message":""}`)))
+	w = httptest.NewRecorder()
+	handleAnnouncement(w, httptest.NewRequest(http.MethodGet, "/announcement", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("announcement after clearing: status %d, want 204", w.Code)
+	}
+}
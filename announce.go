@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxAnnouncementLength es la longitud máxima de un aviso, en caracteres.
+const maxAnnouncementLength = 280
+
+// severities son los niveles aceptados en /admin/announce.
+var severities = []string{"info", "warning", "critical"}
+
+// Announcement es el aviso que los clientes muestran a todos los jugadores, por ejemplo
+// antes de un mantenimiento.
+type Announcement struct {
+	Message   string    `json:"message"`
+	Severity  string    `json:"severity"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+var (
+	announcement   *Announcement
+	announcementMu sync.RWMutex
+)
+
+// handleAnnounce atiende POST /admin/announce con {"message": "...", "severity": "..."}.
+// severity es info si no se indica; un mensaje vacío retira el aviso actual.
+func handleAnnounce(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Message  string `json:"message"`
+		Severity string `json:"severity"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if body.Severity == "" {
+		body.Severity = "info"
+	}
+	if !slices.Contains(severities, body.Severity) {
+		http.Error(w, "Severity must be info, warning or critical", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(body.Message) > maxAnnouncementLength {
+		http.Error(w, "Message must be at most 280 characters", http.StatusBadRequest)
+		return
+	}
+
+	var a *Announcement
+	if body.Message != "" {
+		a = &Announcement{Message: body.Message, Severity: body.Severity, CreatedAt: time.Now()}
+	}
+	announcementMu.Lock()
+	announcement = a
+	announcementMu.Unlock()
+	slog.InfoContext(r.Context(), "announcement set", "severity", body.Severity, "cleared", a == nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	if a == nil {
+		json.NewEncoder(w).Encode(map[string]bool{"cleared": true})
+		return
+	}
+	json.NewEncoder(w).Encode(a)
+}
+
+// handleAnnouncement atiende GET /announcement con el aviso vigente, o 204 si no hay.
+func handleAnnouncement(w http.ResponseWriter, r *http.Request) {
+	announcementMu.RLock()
+	a := announcement
+	announcementMu.RUnlock()
+
+	if a == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a)
+}
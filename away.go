@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// handleAway atiende POST /player/{id}/away. El jugador conserva su posición en el
+// pool pero matchPlayers lo ignora hasta que vuelva.
+func handleAway(w http.ResponseWriter, r *http.Request, playerID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	player, exists := state.players[playerID]
+	if !exists || player.Matched {
+		http.Error(w, "Player not in queue", http.StatusNotFound)
+		return
+	}
+
+	if !player.IsAway {
+		player.IsAway = true
+		player.AwaySince = time.Now()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "away"})
+}
+
+// handleBack atiende POST /player/{id}/back. El tiempo ausente no cuenta como espera.
+func handleBack(w http.ResponseWriter, r *http.Request, playerID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	player, exists := state.players[playerID]
+	if !exists || player.Matched {
+		http.Error(w, "Player not in queue", http.StatusNotFound)
+		return
+	}
+
+	if player.IsAway {
+		player.CreatedAt = player.CreatedAt.Add(time.Since(player.AwaySince))
+		player.IsAway = false
+		player.AwaySince = time.Time{}
+	}
+	player.LastSeen = time.Now()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "waiting"})
+}
+
+// expireAwayPlayers cancela a los jugadores que llevan ausentes más de timeout.
+// Requiere state.mu.
+func expireAwayPlayers(timeout time.Duration) {
+	var expired []string
+	for _, e := range state.pool {
+		if e.Player.IsAway && time.Since(e.Player.AwaySince) > timeout {
+			expired = append(expired, e.Player.ID)
+		}
+	}
+
+	for _, id := range expired {
+		removePlayer(id)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// maxBanDuration es la duración máxima de un baneo.
+const maxBanDuration = 30 * 24 * time.Hour
+
+// isBanned indica si playerID tiene un baneo vigente. Requiere state.mu.
+func isBanned(playerID string, now time.Time) bool {
+	until, ok := state.bans[playerID]
+	return ok && now.Before(until)
+}
+
+// checkBanned responde 403 si playerID está baneado y devuelve false en ese caso.
+func checkBanned(w http.ResponseWriter, playerID string) bool {
+	state.mu.RLock()
+	banned := isBanned(playerID, time.Now())
+	state.mu.RUnlock()
+	if banned {
+		http.Error(w, "Player is banned", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// handleBan atiende POST /admin/players/{id}/ban?duration=1h, que saca al jugador de la
+// cola y le impide volver a entrar durante ese tiempo, y DELETE, que levanta el baneo.
+func handleBan(w http.ResponseWriter, r *http.Request, playerID string) {
+	switch r.Method {
+	case http.MethodPost:
+		duration, err := time.ParseDuration(r.URL.Query().Get("duration"))
+		if err != nil || duration <= 0 || duration > maxBanDuration {
+			http.Error(w, "Duration must be a positive duration up to 720h", http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		until := now.Add(duration)
+		state.mu.Lock()
+		state.bans[playerID] = until
+		if p, ok := state.players[playerID]; ok && !p.Matched {
+			state.cancelNotices[playerID] = cancelNotice{Reason: "banned", At: now}
+			removePlayer(playerID)
+		}
+		state.mu.Unlock()
+		slog.InfoContext(r.Context(), "player banned", "player_id", playerID, "until", until)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"playerID": playerID, "bannedUntil": until})
+	case http.MethodDelete:
+		state.mu.Lock()
+		delete(state.bans, playerID)
+		state.mu.Unlock()
+		slog.InfoContext(r.Context(), "player unbanned", "player_id", playerID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"playerID": playerID, "banned": false})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// expireBans olvida los baneos vencidos. Requiere state.mu.
+func expireBans() {
+	now := time.Now()
+	for id, until := range state.bans {
+		if !now.Before(until) {
+			delete(state.bans, id)
+		}
+	}
+}
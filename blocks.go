@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+)
+
+// maxBlocks es el máximo de jugadores que puede bloquear cada jugador.
+const maxBlocks = 20
+
+// isBlocked indica si a ha bloqueado a b o b a a. Requiere state.mu.
+func isBlocked(a, b string) bool {
+	return slices.Contains(state.blocks[a], b) || slices.Contains(state.blocks[b], a)
+}
+
+// handleBlock atiende POST y DELETE /player/{id}/block/{targetID}.
+func handleBlock(w http.ResponseWriter, r *http.Request, playerID, targetID string) {
+	if targetID == "" {
+		http.Error(w, "Target ID is required", http.StatusBadRequest)
+		return
+	}
+	if targetID == playerID {
+		http.Error(w, "Players cannot block themselves", http.StatusBadRequest)
+		return
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	blocks := state.blocks[playerID]
+	switch r.Method {
+	case http.MethodPost:
+		if !slices.Contains(blocks, targetID) {
+			if len(blocks) >= maxBlocks {
+				http.Error(w, "Block list is full", http.StatusConflict)
+				return
+			}
+			// Copiamos para no modificar el slice que pueda estar leyendo /blocks
+			state.blocks[playerID] = append(slices.Clone(blocks), targetID)
+		}
+	case http.MethodDelete:
+		blocks = slices.DeleteFunc(slices.Clone(blocks), func(id string) bool { return id == targetID })
+		if len(blocks) == 0 {
+			delete(state.blocks, playerID)
+		} else {
+			state.blocks[playerID] = blocks
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"playerID": playerID, "blocks": blockList(playerID)})
+}
+
+// handleBlocks atiende GET /player/{id}/blocks y, para administradores,
+// GET /admin/players/{id}/blocks. Solo lectura.
+func handleBlocks(w http.ResponseWriter, r *http.Request, playerID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state.mu.RLock()
+	blocks := blockList(playerID)
+	state.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"playerID": playerID, "blocks": blocks})
+}
+
+// blockList devuelve los bloqueos de playerID; nunca nil para que el JSON sea [].
+// Requiere state.mu.
+func blockList(playerID string) []string {
+	if blocks := state.blocks[playerID]; blocks != nil {
+		return blocks
+	}
+	return []string{}
+}
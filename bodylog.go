@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// maxLoggedBody es el máximo de bytes del cuerpo que se guardan para el log.
+const maxLoggedBody = 4 << 10
+
+// redactedFields son los campos JSON cuyo valor nunca se registra, en cualquier nivel.
+var redactedFields = []string{"password", "token", "secret"}
+
+// BodyLoggingMiddleware guarda hasta maxLoggedBody bytes del cuerpo de cada petición y,
+// si la respuesta no es 2xx, lo registra en Warn con los campos sensibles ocultos. Las
+// rutas de streaming se excluyen. Desactivado por defecto (Config.BodyLoggingEnabled):
+// los cuerpos pueden contener datos personales.
+func BodyLoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range streamingPrefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		head, err := io.ReadAll(io.LimitReader(r.Body, maxLoggedBody+1))
+		if err != nil {
+			http.Error(w, "Could not read request body", http.StatusBadRequest)
+			return
+		}
+		// El handler sigue leyendo el cuerpo completo, incluido lo que no guardamos
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if rec.status >= 200 && rec.status < 300 || len(head) == 0 {
+			return
+		}
+		truncated := len(head) > maxLoggedBody
+		if truncated {
+			head = head[:maxLoggedBody]
+		}
+		slog.WarnContext(r.Context(), "failed request body", "method", r.Method, "path", r.URL.Path, "status", rec.status,
+			"body", redactBody(head, truncated), "truncated", truncated)
+	})
+}
+
+// redactBody devuelve el cuerpo JSON con los campos sensibles ocultos. Los cuerpos que
+// no son JSON válido, incluidos los truncados, no se registran: no podemos saber qué
+// contienen.
+func redactBody(body []byte, truncated bool) string {
+	var v any
+	if truncated || json.Unmarshal(body, &v) != nil {
+		return "[non-JSON body omitted]"
+	}
+	redacted, _ := json.Marshal(redactValue(v))
+	return string(redacted)
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if isRedactedField(k) {
+				v[k] = "[REDACTED]"
+			} else {
+				v[k] = redactValue(field)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+	return v
+}
+
+func isRedactedField(name string) bool {
+	for _, f := range redactedFields {
+		if strings.EqualFold(name, f) {
+			return true
+		}
+	}
+	return false
+}
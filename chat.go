@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// chatCapacity es el número de mensajes que guarda cada sala; los más antiguos se
+	// sobrescriben.
+	chatCapacity     = 50
+	maxChatLength    = 256
+	maxChatBodyBytes = 4 << 10
+)
+
+// htmlTagPattern reconoce etiquetas HTML para eliminarlas de los mensajes.
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// ChatMessage es un mensaje del chat de una sala.
+type ChatMessage struct {
+	PlayerID string    `json:"playerID"`
+	Text     string    `json:"text"`
+	SentAt   time.Time `json:"sentAt"`
+}
+
+// chatRing es un buffer circular de chatCapacity mensajes. Requiere state.mu.
+type chatRing struct {
+	messages [chatCapacity]ChatMessage
+	next     int // posición donde se escribirá el siguiente mensaje
+	size     int
+}
+
+func (c *chatRing) add(m ChatMessage) {
+	c.messages[c.next] = m
+	c.next = (c.next + 1) % chatCapacity
+	c.size = min(c.size+1, chatCapacity)
+}
+
+// since devuelve, del más antiguo al más reciente, los mensajes posteriores a t.
+func (c *chatRing) since(t time.Time) []ChatMessage {
+	out := make([]ChatMessage, 0, c.size)
+	start := (c.next - c.size + chatCapacity) % chatCapacity
+	for k := 0; k < c.size; k++ {
+		if m := c.messages[(start+k)%chatCapacity]; m.SentAt.After(t) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// sanitizeChat elimina etiquetas HTML y caracteres de control y recorta espacios. El
+// resultado se escapa igualmente al mostrarlo: esto solo evita marcado en el texto.
+func sanitizeChat(text string) string {
+	// Decodificamos antes de quitar etiquetas para que &lt;b&gt; tampoco pase
+	text = html.UnescapeString(text)
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = strings.Map(func(c rune) rune {
+		if unicode.IsControl(c) {
+			return -1
+		}
+		return c
+	}, text)
+	return strings.TrimSpace(text)
+}
+
+// handleChat atiende POST /room/{id}/chat con {"playerID": "...", "message": "..."} y
+// GET /room/{id}/chat?since=<unixms>.
+func handleChat(w http.ResponseWriter, r *http.Request, roomID string) {
+	switch r.Method {
+	case http.MethodPost:
+		handlePostChat(w, r, roomID)
+	case http.MethodGet:
+		handleGetChat(w, r, roomID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func handlePostChat(w http.ResponseWriter, r *http.Request, roomID string) {
+	var body struct {
+		PlayerID string `json:"playerID"`
+		Message  string `json:"message"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxChatBodyBytes)).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if body.PlayerID == "" {
+		http.Error(w, "playerID is required", http.StatusBadRequest)
+		return
+	}
+
+	text := sanitizeChat(body.Message)
+	if text == "" {
+		http.Error(w, "Message must not be empty", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(text) > maxChatLength {
+		http.Error(w, "Message must be at most 256 characters", http.StatusBadRequest)
+		return
+	}
+
+	msg := ChatMessage{PlayerID: body.PlayerID, Text: text, SentAt: time.Now()}
+	state.mu.Lock()
+	room, exists := state.rooms[roomID]
+	if !exists {
+		state.mu.Unlock()
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+	if !slices.Contains(room.Players, body.PlayerID) {
+		state.mu.Unlock()
+		http.Error(w, "Player is not in this room", http.StatusForbidden)
+		return
+	}
+	room.chat.add(msg)
+	room.broadcast(spectatorEvent{Event: "chat", Data: msg})
+	state.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(msg)
+}
+
+func handleGetChat(w http.ResponseWriter, r *http.Request, roomID string) {
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ms < 0 {
+			http.Error(w, "Invalid since", http.StatusBadRequest)
+			return
+		}
+		since = time.UnixMilli(ms)
+	}
+
+	state.mu.RLock()
+	room, exists := state.rooms[roomID]
+	var messages []ChatMessage
+	if exists {
+		messages = room.chat.since(since)
+	}
+	state.mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"roomID":   roomID,
+		"messages": messages,
+	})
+}
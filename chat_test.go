@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChatRingWraparound(t *testing.T) {
+	var ring chatRing
+	base := time.Now()
+	const total = chatCapacity + 7
+	for i := 0; i < total; i++ {
+		ring.add(ChatMessage{PlayerID: "p", Text: fmt.Sprintf("m%d", i), SentAt: base.Add(time.Duration(i) * time.Millisecond)})
+	}
+
+	got := ring.since(time.Time{})
+	if len(got) != chatCapacity {
+		t.Fatalf("ring holds %d messages, want %d", len(got), chatCapacity)
+	}
+	// Los siete primeros se han sobrescrito y el resto sigue en orden de llegada
+	for k, m := range got {
+		if want := fmt.Sprintf("m%d", total-chatCapacity+k); m.Text != want {
+			t.Fatalf("message %d is %q, want %q", k, m.Text, want)
+		}
+	}
+
+	// since filtra sobre el buffer ya rotado
+	recent := ring.since(base.Add(time.Duration(total-3) * time.Millisecond))
+	if len(recent) != 2 || recent[0].Text != fmt.Sprintf("m%d", total-2) || recent[1].Text != fmt.Sprintf("m%d", total-1) {
+		t.Fatalf("since returned %+v, want the last two messages", recent)
+	}
+}
+
+func TestChatHandlerKeepsLatestMessages(t *testing.T) {
+	resetState(t)
+	state.rooms["r1"] = newRoom("r1", []string{"a", "b"}, time.Now())
+
+	const total = chatCapacity + 1
+	for i := 0; i < total; i++ {
+		w := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"playerID": "a", "message": "m%d"}`, i)
+		handleChat(w, httptest.NewRequest(http.MethodPost, "/room/r1/chat", strings.NewReader(body)), "r1")
+		if w.Code != http.StatusCreated {
+			t.Fatalf("post m%d: status %d", i, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handleChat(w, httptest.NewRequest(http.MethodGet, "/room/r1/chat", nil), "r1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("get: status %d", w.Code)
+	}
+	var resp struct {
+		Messages []ChatMessage `json:"messages"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Messages) != chatCapacity {
+		t.Fatalf("got %d messages, want %d", len(resp.Messages), chatCapacity)
+	}
+	if resp.Messages[0].Text != "m1" || resp.Messages[chatCapacity-1].Text != fmt.Sprintf("m%d", total-1) {
+		t.Fatalf("got %q..%q, want m1..m%d", resp.Messages[0].Text, resp.Messages[chatCapacity-1].Text, total-1)
+	}
+}
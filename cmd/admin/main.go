@@ -0,0 +1,432 @@
+// Command admin gestiona un servidor en marcha a través de su API de administración.
+// Lee SERVER_URL (por defecto http://localhost:8080), ADMIN_KEY y ADMIN_TOKEN del
+// entorno. ADMIN_KEY se envía como X-Admin-Key y ADMIN_TOKEN, o --token, como
+// "Authorization: Bearer" para los endpoints que lo exigen.
+//
+// Uso:
+//
+//	admin [--output=text|json] [--token=T] <comando> [flags]
+//
+// Comandos:
+//
+//	players list                         jugadores conocidos por el servidor
+//	players ban --id=X --duration=1h     saca al jugador de la cola y le impide volver
+//	players remove --id=X                saca al jugador de la cola (token)
+//	players anonymize --id=X             anonimiza a un jugador (derecho de supresión)
+//	rooms list                           todas las salas
+//	rooms abort --id=Y                   cierra la sala (token)
+//	pool flush [--reason=R]              vacía la cola avisando a los jugadores
+//	pool drain [--resume]                deja de admitir jugadores nuevos, o vuelve a admitirlos
+//	pool snapshots [--from=T] [--to=T]   capturas de la cola (RFC 3339)
+//	announce --message=M [--severity=S]  aviso para todos los jugadores (info, warning, critical)
+//	stats                                contadores del servidor
+//	history [--page=N]                   historial de partidas
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// target es el servidor al que se envían los comandos y sus credenciales.
+type target struct {
+	URL   string
+	Key   string
+	Token string
+}
+
+// command es un subcomando: construye la petición a partir de sus flags y sabe
+// mostrar la respuesta como texto.
+type command struct {
+	method string
+	build  func(fs *flag.FlagSet, args []string) (path string, body []byte, err error)
+	text   func(out io.Writer, body []byte) error
+}
+
+// requireID registra --id en fs, analiza args y exige que se haya indicado.
+func requireID(fs *flag.FlagSet, args []string, usage string) (string, error) {
+	id := fs.String("id", "", usage)
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if *id == "" {
+		return "", errors.New("--id is required")
+	}
+	return *id, nil
+}
+
+var commands = map[string]command{
+	"players list": {
+		method: http.MethodGet,
+		build: func(fs *flag.FlagSet, args []string) (string, []byte, error) {
+			return "/admin/players", nil, fs.Parse(args)
+		},
+		text: func(out io.Writer, body []byte) error {
+			var players []struct {
+				PlayerID    string  `json:"playerID"`
+				Status      string  `json:"status"`
+				ELO         int     `json:"elo"`
+				WaitSeconds float64 `json:"waitSeconds"`
+				RoomID      string  `json:"roomID"`
+			}
+			if err := json.Unmarshal(body, &players); err != nil {
+				return err
+			}
+			for _, p := range players {
+				fmt.Fprintf(out, "%s  %s  elo=%d  wait=%.0fs  %s\n", p.PlayerID, p.Status, p.ELO, p.WaitSeconds, p.RoomID)
+			}
+			return nil
+		},
+	},
+	"players ban": {
+		method: http.MethodPost,
+		build: func(fs *flag.FlagSet, args []string) (string, []byte, error) {
+			duration := fs.Duration("duration", time.Hour, "how long the ban lasts")
+			id, err := requireID(fs, args, "player ID to ban")
+			if err != nil {
+				return "", nil, err
+			}
+			return "/admin/players/" + url.PathEscape(id) + "/ban?duration=" + url.QueryEscape(duration.String()), nil, nil
+		},
+		text: func(out io.Writer, body []byte) error {
+			var resp struct {
+				PlayerID    string    `json:"playerID"`
+				BannedUntil time.Time `json:"bannedUntil"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Banned %s until %s\n", resp.PlayerID, resp.BannedUntil.Format(time.RFC3339))
+			return nil
+		},
+	},
+	"players remove": {
+		method: http.MethodDelete,
+		build: func(fs *flag.FlagSet, args []string) (string, []byte, error) {
+			id, err := requireID(fs, args, "player ID to remove from the queue")
+			if err != nil {
+				return "", nil, err
+			}
+			return "/admin/player/" + url.PathEscape(id), nil, nil
+		},
+		text: func(out io.Writer, body []byte) error {
+			fmt.Fprintln(out, "Removed")
+			return nil
+		},
+	},
+	"players anonymize": {
+		method: http.MethodPost,
+		build: func(fs *flag.FlagSet, args []string) (string, []byte, error) {
+			id, err := requireID(fs, args, "player ID to anonymize")
+			if err != nil {
+				return "", nil, err
+			}
+			return "/admin/players/" + url.PathEscape(id) + "/anonymize", nil, nil
+		},
+		text: func(out io.Writer, body []byte) error {
+			var resp struct {
+				AnonymizedID string `json:"anonymizedID"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return err
+			}
+			fmt.Fprintln(out, "Anonymized as", resp.AnonymizedID)
+			return nil
+		},
+	},
+	"rooms list": {
+		method: http.MethodGet,
+		build: func(fs *flag.FlagSet, args []string) (string, []byte, error) {
+			return "/admin/rooms", nil, fs.Parse(args)
+		},
+		text: func(out io.Writer, body []byte) error {
+			var rooms []struct {
+				ID        string    `json:"id"`
+				Players   []string  `json:"players"`
+				State     string    `json:"state"`
+				CreatedAt time.Time `json:"createdAt"`
+			}
+			if err := json.Unmarshal(body, &rooms); err != nil {
+				return err
+			}
+			for _, r := range rooms {
+				fmt.Fprintf(out, "%s  %s  %s  %s\n", r.CreatedAt.Format(time.RFC3339), r.State, r.ID, strings.Join(r.Players, ","))
+			}
+			return nil
+		},
+	},
+	"rooms abort": {
+		method: http.MethodDelete,
+		build: func(fs *flag.FlagSet, args []string) (string, []byte, error) {
+			id, err := requireID(fs, args, "room ID to close")
+			if err != nil {
+				return "", nil, err
+			}
+			return "/admin/room/" + url.PathEscape(id), nil, nil
+		},
+		text: func(out io.Writer, body []byte) error {
+			fmt.Fprintln(out, "Room closed")
+			return nil
+		},
+	},
+	"pool flush": {
+		method: http.MethodPost,
+		build: func(fs *flag.FlagSet, args []string) (string, []byte, error) {
+			reason := fs.String("reason", "", "reason reported to the players (default server_maintenance)")
+			if err := fs.Parse(args); err != nil {
+				return "", nil, err
+			}
+			return "/admin/pool/flush?reason=" + url.QueryEscape(*reason), nil, nil
+		},
+		text: func(out io.Writer, body []byte) error {
+			var resp struct {
+				Flushed int `json:"flushed"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Flushed %d players\n", resp.Flushed)
+			return nil
+		},
+	},
+	"pool drain": {
+		method: http.MethodPost,
+		build: func(fs *flag.FlagSet, args []string) (string, []byte, error) {
+			resume := fs.Bool("resume", false, "accept new players again")
+			if err := fs.Parse(args); err != nil {
+				return "", nil, err
+			}
+			return fmt.Sprintf("/admin/pool/drain?enabled=%t", !*resume), nil, nil
+		},
+		text: func(out io.Writer, body []byte) error {
+			var resp struct {
+				Draining bool `json:"draining"`
+				Waiting  int  `json:"waiting"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return err
+			}
+			if resp.Draining {
+				fmt.Fprintf(out, "Draining, %d players still waiting\n", resp.Waiting)
+			} else {
+				fmt.Fprintln(out, "Accepting new players")
+			}
+			return nil
+		},
+	},
+	"pool snapshots": {
+		method: http.MethodGet,
+		build: func(fs *flag.FlagSet, args []string) (string, []byte, error) {
+			from := fs.String("from", "", "only snapshots at or after this RFC 3339 time")
+			to := fs.String("to", "", "only snapshots at or before this RFC 3339 time")
+			if err := fs.Parse(args); err != nil {
+				return "", nil, err
+			}
+			q := url.Values{}
+			if *from != "" {
+				q.Set("from", *from)
+			}
+			if *to != "" {
+				q.Set("to", *to)
+			}
+			return "/admin/queue-snapshots?" + q.Encode(), nil, nil
+		},
+		text: func(out io.Writer, body []byte) error {
+			var snapshots []struct {
+				Timestamp time.Time `json:"timestamp"`
+				PoolSize  int       `json:"poolSize"`
+			}
+			if err := json.Unmarshal(body, &snapshots); err != nil {
+				return err
+			}
+			for _, s := range snapshots {
+				fmt.Fprintf(out, "%s  %d waiting\n", s.Timestamp.Format(time.RFC3339), s.PoolSize)
+			}
+			return nil
+		},
+	},
+	"announce": {
+		method: http.MethodPost,
+		build: func(fs *flag.FlagSet, args []string) (string, []byte, error) {
+			message := fs.String("message", "", "announcement text; empty clears the current one")
+			severity := fs.String("severity", "info", "info, warning or critical")
+			if err := fs.Parse(args); err != nil {
+				return "", nil, err
+			}
+			body, err := json.Marshal(map[string]string{"message": *message, "severity": *severity})
+			return "/admin/announce", body, err
+		},
+		text: func(out io.Writer, body []byte) error {
+			var resp struct {
+				Message  string `json:"message"`
+				Severity string `json:"severity"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return err
+			}
+			if resp.Message == "" {
+				fmt.Fprintln(out, "Announcement cleared")
+			} else {
+				fmt.Fprintf(out, "Announced (%s): %s\n", resp.Severity, resp.Message)
+			}
+			return nil
+		},
+	},
+	"stats": {
+		method: http.MethodGet,
+		build: func(fs *flag.FlagSet, args []string) (string, []byte, error) {
+			return "/stats?format=json", nil, fs.Parse(args)
+		},
+		text: func(out io.Writer, body []byte) error {
+			var s struct {
+				TotalPlayers   int     `json:"totalPlayers"`
+				WaitingPlayers int     `json:"waitingPlayers"`
+				AwayPlayers    int     `json:"awayPlayers"`
+				MatchedPlayers int     `json:"matchedPlayers"`
+				ActiveRooms    int     `json:"activeRooms"`
+				Spectators     int     `json:"spectators"`
+				AvgWaitSeconds float64 `json:"avgWaitSeconds"`
+				MaxWaitSeconds float64 `json:"maxWaitSeconds"`
+			}
+			if err := json.Unmarshal(body, &s); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "players: %d (%d waiting, %d away, %d matched)\n", s.TotalPlayers, s.WaitingPlayers, s.AwayPlayers, s.MatchedPlayers)
+			fmt.Fprintf(out, "rooms: %d active, %d spectators\n", s.ActiveRooms, s.Spectators)
+			fmt.Fprintf(out, "wait: %.1fs average, %.1fs max\n", s.AvgWaitSeconds, s.MaxWaitSeconds)
+			return nil
+		},
+	},
+	"history": {
+		method: http.MethodGet,
+		build: func(fs *flag.FlagSet, args []string) (string, []byte, error) {
+			page := fs.Int("page", 1, "page number")
+			if err := fs.Parse(args); err != nil {
+				return "", nil, err
+			}
+			return fmt.Sprintf("/history?page=%d", *page), nil, nil
+		},
+		text: func(out io.Writer, body []byte) error {
+			var resp struct {
+				Matches []struct {
+					RoomID    string     `json:"roomID"`
+					Players   []string   `json:"players"`
+					CreatedAt time.Time  `json:"createdAt"`
+					EndedAt   *time.Time `json:"endedAt"`
+				} `json:"matches"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return err
+			}
+			for _, m := range resp.Matches {
+				ended := "open"
+				if m.EndedAt != nil {
+					ended = m.EndedAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(out, "%s  %s  %s  %s\n", m.CreatedAt.Format(time.RFC3339), ended, m.RoomID, strings.Join(m.Players, ","))
+			}
+			return nil
+		},
+	},
+}
+
+func main() {
+	output := flag.String("output", "text", "output format: text or json")
+	token := flag.String("token", os.Getenv("ADMIN_TOKEN"), "bearer token for players remove and rooms abort (default $ADMIN_TOKEN)")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: admin [--output=text|json] [--token=T] <command> [flags]")
+		fmt.Fprintln(os.Stderr, "commands: players list|ban|remove|anonymize, rooms list|abort, pool flush|drain|snapshots, announce, stats, history")
+	}
+	flag.Parse()
+
+	if *output != "text" && *output != "json" {
+		fmt.Fprintln(os.Stderr, "--output must be text or json")
+		os.Exit(2)
+	}
+
+	name, cmd, args, ok := lookup(flag.Args())
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	t := target{URL: os.Getenv("SERVER_URL"), Key: os.Getenv("ADMIN_KEY"), Token: *token}
+	if t.URL == "" {
+		t.URL = "http://localhost:8080"
+	}
+
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	if err := run(os.Stdout, t, fs, cmd, args, *output == "json"); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// lookup busca el subcomando de una o dos palabras al principio de args.
+func lookup(args []string) (string, command, []string, bool) {
+	if len(args) >= 2 {
+		if cmd, ok := commands[args[0]+" "+args[1]]; ok {
+			return args[0] + " " + args[1], cmd, args[2:], true
+		}
+	}
+	if len(args) >= 1 {
+		if cmd, ok := commands[args[0]]; ok {
+			return args[0], cmd, args[1:], true
+		}
+	}
+	return "", command{}, nil, false
+}
+
+// run ejecuta cmd contra t y escribe la respuesta en out. fs recibe los flags del
+// subcomando.
+func run(out io.Writer, t target, fs *flag.FlagSet, cmd command, args []string, rawJSON bool) error {
+	path, reqBody, err := cmd.build(fs, args)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(cmd.method, strings.TrimRight(t.URL, "/")+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if t.Key != "" {
+		req.Header.Set("X-Admin-Key", t.Key)
+	}
+	if t.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.Token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if rawJSON {
+		_, err := out.Write(body)
+		return err
+	}
+	return cmd.text(out, body)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		args     []string
+		method   string
+		uri      string
+		body     string
+		response string
+		output   string
+	}{
+		{
+			args:     []string{"players", "list"},
+			method:   http.MethodGet,
+			uri:      "/admin/players",
+			response: `[{"playerID":"p1","status":"waiting","elo":1200,"waitSeconds":3}]`,
+			output:   "p1  waiting  elo=1200  wait=3s",
+		},
+		{
+			args:     []string{"players", "ban", "--id=p1", "--duration=90m"},
+			method:   http.MethodPost,
+			uri:      "/admin/players/p1/ban?duration=1h30m0s",
+			response: `{"playerID":"p1","bannedUntil":"2026-01-01T10:00:00Z"}`,
+			output:   "Banned p1 until 2026-01-01T10:00:00Z",
+		},
+		{
+			args:     []string{"players", "remove", "--id=p 1"},
+			method:   http.MethodDelete,
+			uri:      "/admin/player/p%201",
+			response: `{"removed":true}`,
+			output:   "Removed",
+		},
+		{
+			args:     []string{"players", "anonymize", "--id=p1"},
+			method:   http.MethodPost,
+			uri:      "/admin/players/p1/anonymize",
+			response: `{"anonymizedID":"deleted_user_abc"}`,
+			output:   "Anonymized as deleted_user_abc",
+		},
+		{
+			args:     []string{"rooms", "list"},
+			method:   http.MethodGet,
+			uri:      "/admin/rooms",
+			response: `[{"id":"r1","players":["a","b"],"state":"ACTIVE","createdAt":"2026-01-01T10:00:00Z"}]`,
+			output:   "2026-01-01T10:00:00Z  ACTIVE  r1  a,b",
+		},
+		{
+			args:     []string{"rooms", "abort", "--id=r1"},
+			method:   http.MethodDelete,
+			uri:      "/admin/room/r1",
+			response: `{"removed":true}`,
+			output:   "Room closed",
+		},
+		{
+			args:     []string{"pool", "flush", "--reason=maintenance"},
+			method:   http.MethodPost,
+			uri:      "/admin/pool/flush?reason=maintenance",
+			response: `{"flushed":3}`,
+			output:   "Flushed 3 players",
+		},
+		{
+			args:     []string{"pool", "drain"},
+			method:   http.MethodPost,
+			uri:      "/admin/pool/drain?enabled=true",
+			response: `{"draining":true,"waiting":2}`,
+			output:   "Draining, 2 players still waiting",
+		},
+		{
+			args:     []string{"pool", "drain", "--resume"},
+			method:   http.MethodPost,
+			uri:      "/admin/pool/drain?enabled=false",
+			response: `{"draining":false,"waiting":2}`,
+			output:   "Accepting new players",
+		},
+		{
+			args:     []string{"pool", "snapshots", "--from=2026-01-01T00:00:00Z"},
+			method:   http.MethodGet,
+			uri:      "/admin/queue-snapshots?from=2026-01-01T00%3A00%3A00Z",
+			response: `[{"timestamp":"2026-01-01T10:00:00Z","poolSize":4}]`,
+			output:   "2026-01-01T10:00:00Z  4 waiting",
+		},
+		{
+			args:     []string{"announce", "--message=maintenance in 5m", "--severity=warning"},
+			method:   http.MethodPost,
+			uri:      "/admin/announce",
+			body:     `{"message":"maintenance in 5m","severity":"warning"}`,
+			response: `{"message":"maintenance in 5m","severity":"warning"}`,
+			output:   "Announced (warning): maintenance in 5m",
+		},
+		{
+			args:     []string{"stats"},
+			method:   http.MethodGet,
+			uri:      "/stats?format=json",
+			response: `{"totalPlayers":5,"waitingPlayers":2,"awayPlayers":1,"matchedPlayers":2,"activeRooms":1}`,
+			output:   "players: 5 (2 waiting, 1 away, 2 matched)",
+		},
+		{
+			args:     []string{"history", "--page=2"},
+			method:   http.MethodGet,
+			uri:      "/history?page=2",
+			response: `{"matches":[{"roomID":"r1","players":["a","b"],"createdAt":"2026-01-01T10:00:00Z"}]}`,
+			output:   "2026-01-01T10:00:00Z  open  r1  a,b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			var got *http.Request
+			var gotBody string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				got, gotBody = r, string(b)
+				io.WriteString(w, tt.response)
+			}))
+			defer srv.Close()
+
+			name, cmd, args, ok := lookup(tt.args)
+			if !ok {
+				t.Fatalf("unknown command %v", tt.args)
+			}
+			var out bytes.Buffer
+			tgt := target{URL: srv.URL, Key: "key", Token: "token"}
+			if err := run(&out, tgt, flag.NewFlagSet(name, flag.ContinueOnError), cmd, args, false); err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Method != tt.method || got.RequestURI != tt.uri {
+				t.Errorf("request = %s %s, want %s %s", got.Method, got.RequestURI, tt.method, tt.uri)
+			}
+			if gotBody != tt.body {
+				t.Errorf("body = %q, want %q", gotBody, tt.body)
+			}
+			if got.Header.Get("X-Admin-Key") != "key" || got.Header.Get("Authorization") != "Bearer token" {
+				t.Errorf("credentials not sent: %v", got.Header)
+			}
+			if !strings.Contains(out.String(), tt.output) {
+				t.Errorf("output = %q, want it to contain %q", out.String(), tt.output)
+			}
+		})
+	}
+}
+
+func TestRunJSONOutputAndErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, `{"removed":true}`)
+	}))
+	defer srv.Close()
+
+	_, cmd, args, _ := lookup([]string{"rooms", "abort", "--id=r1"})
+
+	var out bytes.Buffer
+	err := run(&out, target{URL: srv.URL}, flag.NewFlagSet("rooms abort", flag.ContinueOnError), cmd, args, true)
+	if err == nil || !strings.Contains(err.Error(), "HTTP 401") {
+		t.Fatalf("run without token: err = %v, want HTTP 401", err)
+	}
+
+	out.Reset()
+	err = run(&out, target{URL: srv.URL, Token: "t"}, flag.NewFlagSet("rooms abort", flag.ContinueOnError), cmd, args, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != `{"removed":true}` {
+		t.Errorf("json output = %q", out.String())
+	}
+}
+
+func TestMissingID(t *testing.T) {
+	for _, name := range []string{"players ban", "players remove", "players anonymize", "rooms abort"} {
+		cmd := commands[name]
+		if _, _, err := cmd.build(flag.NewFlagSet(name, flag.ContinueOnError), nil); err == nil {
+			t.Errorf("%s without --id: no error", name)
+		}
+	}
+}
@@ -0,0 +1,391 @@
+// Command test-integration recorre el ciclo completo de emparejamiento contra un
+// servidor en marcha: dos jugadores entran en cola, esperan la partida, comprueban
+// que comparten sala, juegan con /roll e informan del resultado con /report-result
+// (ADMIN_TOKEN). Después comprueba que dos /join simultáneos con el mismo ID
+// dejan un solo jugador en cola, que el chat de una sala conserva solo los últimos
+// 50 mensajes, que /export/stats devuelve un CSV bien formado y que un jugador que
+// entra por gRPC (GRPC_ADDR) se empareja con otro de /join. Sale con código 1 si algo
+// falla.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"nombre_del_modulo/matchmakerpb"
+)
+
+// timeout es el tiempo máximo de la prueba completa.
+const timeout = 60 * time.Second
+
+var client = &http.Client{Timeout: 5 * time.Second}
+
+// matchStatus es la respuesta de /status/{id}.
+type matchStatus struct {
+	Status  string   `json:"status"`
+	Players []string `json:"players"`
+	RoomID  string   `json:"roomID"`
+}
+
+func main() {
+	serverURL := os.Getenv("SERVER_URL")
+	if serverURL == "" {
+		serverURL = "http://localhost:8080"
+	}
+
+	grpcAddr := os.Getenv("GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = "localhost:9090"
+	}
+	runGRPCAt := func(serverURL string) error { return runGRPC(serverURL, grpcAddr) }
+	runGameAt := func(serverURL string) error { return runGame(serverURL, os.Getenv("ADMIN_TOKEN")) }
+
+	for _, test := range []func(string) error{run, runGameAt, runDuplicateJoin, runChatRing, runExportStats, runGRPCAt} {
+		if err := test(serverURL); err != nil {
+			fmt.Fprintln(os.Stderr, "FAIL:", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Println("PASS")
+}
+
+func run(serverURL string) error {
+	_, err := matchPair(serverURL, fmt.Sprintf("modo_it_%d", time.Now().UnixNano()))
+	return err
+}
+
+// matchPair pone en cola a dos jugadores con el mismo sufijo de modo y espera a que
+// compartan sala. Devuelve el ID de la sala.
+func matchPair(serverURL, suffix string) (string, error) {
+	p1, p2 := "p1_"+suffix, "p2_"+suffix
+
+	for _, id := range []string{p1, p2} {
+		var resp map[string]string
+		if err := getJSON(serverURL+"/join?id="+url.QueryEscape(id), &resp); err != nil {
+			return "", fmt.Errorf("join %s: %w", id, err)
+		}
+		if resp["status"] != "waiting" {
+			return "", fmt.Errorf("join %s: unexpected status %q", id, resp["status"])
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	m1, err := waitForMatch(serverURL, p1, deadline)
+	if err != nil {
+		return "", err
+	}
+	m2, err := waitForMatch(serverURL, p2, deadline)
+	if err != nil {
+		return "", err
+	}
+
+	if !slices.Equal(m1.Players, []string{p2}) || !slices.Equal(m2.Players, []string{p1}) {
+		return "", fmt.Errorf("players not paired together: %v / %v", m1.Players, m2.Players)
+	}
+	if m1.RoomID == "" || m1.RoomID != m2.RoomID {
+		return "", fmt.Errorf("players in different rooms: %q / %q", m1.RoomID, m2.RoomID)
+	}
+	return m1.RoomID, nil
+}
+
+// runGame empareja a dos jugadores, tira los dados por turnos y informa del ganador.
+// Comprueba que no se puede tirar fuera de turno, que /room/{id}/rolls guarda las
+// tiradas y que tras el resultado la sala se cierra y el ganador suma una victoria.
+func runGame(serverURL, adminToken string) error {
+	suffix := fmt.Sprintf("modo_game_it_%d", time.Now().UnixNano())
+	p1, p2 := "p1_"+suffix, "p2_"+suffix
+	roomID, err := matchPair(serverURL, suffix)
+	if err != nil {
+		return fmt.Errorf("game: %w", err)
+	}
+
+	roll := func(playerID string) (int, error) {
+		return post(serverURL+"/roll?room="+url.QueryEscape(roomID)+"&player="+url.QueryEscape(playerID)+"&dice=2", "")
+	}
+	for _, step := range []struct {
+		player string
+		want   int
+	}{
+		{p1, http.StatusOK},
+		{p1, http.StatusConflict}, // no es su turno
+		{p2, http.StatusOK},
+	} {
+		code, err := roll(step.player)
+		if err != nil {
+			return fmt.Errorf("roll %s: %w", step.player, err)
+		}
+		if code != step.want {
+			return fmt.Errorf("roll %s: expected HTTP %d, got %d", step.player, step.want, code)
+		}
+	}
+
+	var rolls struct {
+		Rolls []struct {
+			PlayerID string `json:"playerID"`
+		} `json:"rolls"`
+	}
+	if err := getJSON(serverURL+"/room/"+url.PathEscape(roomID)+"/rolls", &rolls); err != nil {
+		return fmt.Errorf("rolls: %w", err)
+	}
+	if len(rolls.Rolls) != 2 || rolls.Rolls[0].PlayerID != p1 || rolls.Rolls[1].PlayerID != p2 {
+		return fmt.Errorf("rolls: expected one roll each from %s and %s, got %+v", p1, p2, rolls.Rolls)
+	}
+
+	resultURL := serverURL + "/report-result?room=" + url.QueryEscape(roomID) + "&winner=" + url.QueryEscape(p1)
+	if code, err := post(resultURL, adminToken); err != nil || code != http.StatusOK {
+		return fmt.Errorf("report result: HTTP %d, %v", code, err)
+	}
+	// La sala ya no existe: un segundo informe no debe contar dos veces
+	if code, err := post(resultURL, adminToken); err != nil || code != http.StatusNotFound {
+		return fmt.Errorf("second report result: expected HTTP 404, got %d, %v", code, err)
+	}
+
+	var board struct {
+		Players []struct {
+			PlayerID string `json:"playerID"`
+			Wins     int    `json:"wins"`
+		} `json:"players"`
+	}
+	if err := getJSON(serverURL+"/leaderboard?limit=100", &board); err != nil {
+		return fmt.Errorf("leaderboard: %w", err)
+	}
+	for _, e := range board.Players {
+		if e.PlayerID == p1 && e.Wins == 1 {
+			return nil
+		}
+	}
+	return fmt.Errorf("leaderboard: %s missing or without its win", p1)
+}
+
+// runDuplicateJoin lanza dos /join a la vez con el mismo ID y espera exactamente un
+// 200 y un 409.
+func runDuplicateJoin(serverURL string) error {
+	id := fmt.Sprintf("dup_modo_it_%d", time.Now().UnixNano())
+	joinURL := serverURL + "/join?id=" + url.QueryEscape(id)
+
+	codes := make([]int, 2)
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for k := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(joinURL)
+			if err != nil {
+				errs[k] = err
+				return
+			}
+			resp.Body.Close()
+			codes[k] = resp.StatusCode
+		}()
+	}
+	wg.Wait()
+
+	// Lo sacamos de la cola para no dejarlo esperando en el servidor
+	if resp, err := client.Get(serverURL + "/cancel?id=" + url.QueryEscape(id)); err == nil {
+		resp.Body.Close()
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return fmt.Errorf("duplicate join: %w", err)
+		}
+	}
+	slices.Sort(codes)
+	if !slices.Equal(codes, []int{http.StatusOK, http.StatusConflict}) {
+		return fmt.Errorf("duplicate join: expected one 200 and one 409, got %v", codes)
+	}
+	return nil
+}
+
+// runChatRing envía 55 mensajes al chat de una sala y espera recuperar solo los 50
+// últimos, en orden.
+func runChatRing(serverURL string) error {
+	suffix := fmt.Sprintf("modo_chat_it_%d", time.Now().UnixNano())
+	roomID, err := matchPair(serverURL, suffix)
+	if err != nil {
+		return fmt.Errorf("chat: %w", err)
+	}
+
+	chatURL := serverURL + "/room/" + url.PathEscape(roomID) + "/chat"
+	const sent, kept = 55, 50
+	for k := 0; k < sent; k++ {
+		body, _ := json.Marshal(map[string]string{"playerID": "p1_" + suffix, "message": fmt.Sprintf("msg %d", k)})
+		resp, err := client.Post(chatURL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("chat post: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			return fmt.Errorf("chat post: HTTP %d", resp.StatusCode)
+		}
+	}
+
+	var resp struct {
+		Messages []struct {
+			Text string `json:"text"`
+		} `json:"messages"`
+	}
+	if err := getJSON(chatURL, &resp); err != nil {
+		return fmt.Errorf("chat get: %w", err)
+	}
+	if len(resp.Messages) != kept {
+		return fmt.Errorf("chat: expected %d messages, got %d", kept, len(resp.Messages))
+	}
+	for k, m := range resp.Messages {
+		if want := fmt.Sprintf("msg %d", sent-kept+k); m.Text != want {
+			return fmt.Errorf("chat: message %d is %q, want %q", k, m.Text, want)
+		}
+	}
+	return nil
+}
+
+// exportColumns es el número de columnas de /export/stats.
+const exportColumns = 6
+
+// runExportStats descarga /export/stats y comprueba que todas las filas, cabecera
+// incluida, tienen exportColumns columnas y que hay al menos una partida.
+func runExportStats(serverURL string) error {
+	resp, err := client.Get(serverURL + "/export/stats")
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("export: HTTP %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		return fmt.Errorf("export: unexpected Content-Type %q", ct)
+	}
+
+	reader := csv.NewReader(resp.Body)
+	reader.FieldsPerRecord = exportColumns
+	records, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	if len(records) < 2 || records[0][0] != "room_id" {
+		return fmt.Errorf("export: expected a header and at least one match, got %d rows", len(records))
+	}
+	return nil
+}
+
+// runGRPC pone en cola a un jugador por gRPC y a otro por /join, y comprueba que
+// WatchStatus informa de la partida con la misma sala que /status.
+func runGRPC(serverURL, grpcAddr string) error {
+	suffix := fmt.Sprintf("modo_grpc_%d", time.Now().UnixNano())
+	p1, p2 := "p1_"+suffix, "p2_"+suffix
+
+	conn, err := grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("grpc dial: %w", err)
+	}
+	defer conn.Close()
+	mm := matchmakerpb.NewMatchmakerClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	joined, err := mm.JoinQueue(ctx, &matchmakerpb.JoinRequest{PlayerId: p1})
+	if err != nil {
+		return fmt.Errorf("grpc join %s: %w", p1, err)
+	}
+	if joined.GetStatus() != "waiting" {
+		return fmt.Errorf("grpc join %s: unexpected status %q", p1, joined.GetStatus())
+	}
+
+	stream, err := mm.WatchStatus(ctx, &matchmakerpb.StatusRequest{PlayerId: p1})
+	if err != nil {
+		return fmt.Errorf("grpc watch %s: %w", p1, err)
+	}
+
+	var resp map[string]string
+	if err := getJSON(serverURL+"/join?id="+url.QueryEscape(p2), &resp); err != nil {
+		return fmt.Errorf("join %s: %w", p2, err)
+	}
+
+	var event *matchmakerpb.StatusEvent
+	for {
+		event, err = stream.Recv()
+		if err != nil {
+			return fmt.Errorf("grpc watch %s: %w", p1, err)
+		}
+		if event.GetStatus() != "waiting" && event.GetStatus() != "heartbeat" {
+			break
+		}
+	}
+	if event.GetStatus() != "matched" {
+		return fmt.Errorf("grpc watch %s: unexpected status %q", p1, event.GetStatus())
+	}
+
+	m2, err := waitForMatch(serverURL, p2, time.Now().Add(timeout))
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(event.GetPlayers(), []string{p2}) || !slices.Equal(m2.Players, []string{p1}) {
+		return fmt.Errorf("grpc players not paired together: %v / %v", event.GetPlayers(), m2.Players)
+	}
+	if event.GetRoomId() == "" || event.GetRoomId() != m2.RoomID {
+		return fmt.Errorf("grpc room mismatch: %q / %q", event.GetRoomId(), m2.RoomID)
+	}
+	return nil
+}
+
+// waitForMatch consulta /status hasta que el jugador esté emparejado o venza deadline.
+func waitForMatch(serverURL, playerID string, deadline time.Time) (matchStatus, error) {
+	for time.Now().Before(deadline) {
+		var resp matchStatus
+		if err := getJSON(serverURL+"/status/"+url.PathEscape(playerID), &resp); err != nil {
+			return matchStatus{}, fmt.Errorf("status %s: %w", playerID, err)
+		}
+		if resp.Status == "matched" {
+			return resp, nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return matchStatus{}, fmt.Errorf("status %s: no match before timeout", playerID)
+}
+
+// post envía un POST sin cuerpo, con el token de administración si no está vacío, y
+// devuelve el código de estado.
+func post(u, adminToken string) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	if adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func getJSON(u string, v any) error {
+	resp, err := client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config reúne la configuración del servidor. Se carga en este orden, cada fuente
+// sobre la anterior: valores por defecto, fichero YAML de --config, variables de
+// entorno y flags de la línea de comandos.
+type Config struct {
+	Port int `yaml:"port"`
+	// GRPCPort es el puerto del transporte gRPC; 0 lo desactiva.
+	GRPCPort     int    `yaml:"grpc_port"`
+	DatabasePath string `yaml:"database_path"`
+	LogLevel     string `yaml:"log_level"`
+
+	// RoomSize es el número de jugadores por sala.
+	RoomSize int `yaml:"room_size"`
+	// ELOWindow es la diferencia de ELO aceptada al entrar en cola; crece en
+	// ELOWindowStep cada eloWindowInterval de espera.
+	ELOWindow     int `yaml:"elo_window"`
+	ELOWindowStep int `yaml:"elo_window_step"`
+	// PlatformTimeout es el tiempo que un jugador espera rival de su plataforma antes
+	// de aceptar uno de otra.
+	PlatformTimeout time.Duration `yaml:"platform_timeout"`
+	// AwayTimeout es el tiempo máximo que un jugador puede estar ausente antes de ser
+	// retirado de la cola.
+	AwayTimeout time.Duration `yaml:"away_timeout"`
+	// MaxPoolSize es el número máximo de jugadores en cola.
+	MaxPoolSize int `yaml:"max_pool_size"`
+	// InviteExpiry es el tiempo que una sala privada espera a su segundo jugador.
+	InviteExpiry time.Duration `yaml:"invite_expiry"`
+	// RoomTimeout es el tiempo tras el cual una sala activa sin terminar se da por
+	// abandonada y se expira.
+	RoomTimeout time.Duration `yaml:"room_timeout"`
+
+	// CleanupInterval es la frecuencia de cleanupOldRooms.
+	CleanupInterval       time.Duration `yaml:"cleanup_interval"`
+	QueueSnapshotInterval time.Duration `yaml:"queue_snapshot_interval"`
+	// DashboardRefreshMS es el intervalo de refresco de /stats en el dashboard. Un
+	// valor fuera de 200..30000 se rechaza al validar y el servidor no arranca.
+	DashboardRefreshMS int `yaml:"dashboard_refresh_ms"`
+	// MaxGoroutines es el número de goroutines a partir del cual se rechazan peticiones.
+	MaxGoroutines int `yaml:"max_goroutines"`
+
+	ReadTimeout time.Duration `yaml:"read_timeout"`
+	// WriteTimeout es 0 por defecto: SSE, websockets y pprof mantienen respuestas largas.
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+
+	// AdminKey protege los endpoints que usan X-Admin-Key y AdminToken los que usan
+	// Authorization: Bearer. Vacíos, esos endpoints responden 401.
+	AdminKey   string `yaml:"admin_key"`
+	AdminToken string `yaml:"admin_token"`
+
+	// CORSOrigins son los orígenes que pueden llamar a la API desde un navegador. "*"
+	// admite cualquiera; vacía, solo el propio dashboard.
+	CORSOrigins []string `yaml:"cors_origins"`
+
+	// PprofEnabled expone /debug/pprof/ y BodyLoggingEnabled activa
+	// BodyLoggingMiddleware. Ambos desactivados por defecto.
+	PprofEnabled       bool `yaml:"pprof_enabled"`
+	BodyLoggingEnabled bool `yaml:"body_logging_enabled"`
+
+	// SMTPHost activa los avisos por correo; vacío, no se envía ninguno.
+	SMTPHost string `yaml:"smtp_host"`
+	SMTPPort int    `yaml:"smtp_port"`
+	SMTPUser string `yaml:"smtp_user"`
+	SMTPPass string `yaml:"smtp_pass"`
+	// PublicURL es la dirección pública del servidor, para los enlaces de los correos.
+	PublicURL string `yaml:"public_url"`
+	// EmailSecret firma los enlaces de baja; vacío, se genera uno por proceso.
+	EmailSecret string `yaml:"email_secret"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		Port:                  8080,
+		GRPCPort:              9090,
+		DatabasePath:          "diceball.db",
+		LogLevel:              "info",
+		RoomSize:              2,
+		ELOWindow:             200,
+		ELOWindowStep:         50,
+		PlatformTimeout:       60 * time.Second,
+		AwayTimeout:           5 * time.Minute,
+		MaxPoolSize:           1000,
+		InviteExpiry:          60 * time.Second,
+		RoomTimeout:           time.Hour,
+		CleanupInterval:       5 * time.Minute,
+		QueueSnapshotInterval: 10 * time.Second,
+		DashboardRefreshMS:    1000,
+		MaxGoroutines:         10000,
+		ReadTimeout:           10 * time.Second,
+		IdleTimeout:           120 * time.Second,
+		SMTPPort:              587,
+		PublicURL:             "http://localhost:8080",
+	}
+}
+
+// bindFlags registra en fs un flag por cada campo de cfg, usando sus valores actuales
+// como valores por defecto.
+func bindFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.IntVar(&cfg.Port, "port", cfg.Port, "HTTP listen port")
+	fs.IntVar(&cfg.GRPCPort, "grpc-port", cfg.GRPCPort, "gRPC listen port, 0 to disable")
+	fs.StringVar(&cfg.DatabasePath, "database-path", cfg.DatabasePath, "SQLite match history file")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "log level: debug, info, warn or error")
+	fs.IntVar(&cfg.RoomSize, "room-size", cfg.RoomSize, "players per room")
+	fs.IntVar(&cfg.ELOWindow, "elo-window", cfg.ELOWindow, "initial ELO difference accepted when matching")
+	fs.IntVar(&cfg.ELOWindowStep, "elo-window-step", cfg.ELOWindowStep, "ELO window growth per 10 seconds of waiting")
+	fs.DurationVar(&cfg.PlatformTimeout, "platform-timeout", cfg.PlatformTimeout, "wait before accepting opponents from other platforms")
+	fs.DurationVar(&cfg.AwayTimeout, "away-timeout", cfg.AwayTimeout, "maximum time a player may stay away")
+	fs.IntVar(&cfg.MaxPoolSize, "max-pool-size", cfg.MaxPoolSize, "maximum number of players waiting in the pool")
+	fs.DurationVar(&cfg.InviteExpiry, "invite-expiry", cfg.InviteExpiry, "time a private room waits for its guest")
+	fs.DurationVar(&cfg.RoomTimeout, "room-timeout", cfg.RoomTimeout, "time after which an unfinished active room expires")
+	fs.DurationVar(&cfg.CleanupInterval, "cleanup-interval", cfg.CleanupInterval, "interval between room cleanups")
+	fs.DurationVar(&cfg.QueueSnapshotInterval, "queue-snapshot-interval", cfg.QueueSnapshotInterval, "interval between queue snapshots")
+	fs.IntVar(&cfg.DashboardRefreshMS, "dashboard-refresh-ms", cfg.DashboardRefreshMS, "dashboard refresh interval in milliseconds, 200 to 30000")
+	fs.IntVar(&cfg.MaxGoroutines, "max-goroutines", cfg.MaxGoroutines, "goroutine count above which requests are rejected")
+	fs.DurationVar(&cfg.ReadTimeout, "read-timeout", cfg.ReadTimeout, "maximum time to read a request")
+	fs.DurationVar(&cfg.WriteTimeout, "write-timeout", cfg.WriteTimeout, "maximum time to write a response (0 disables)")
+	fs.DurationVar(&cfg.IdleTimeout, "idle-timeout", cfg.IdleTimeout, "maximum keep-alive idle time")
+	fs.StringVar(&cfg.AdminKey, "admin-key", cfg.AdminKey, "key for endpoints using X-Admin-Key")
+	fs.StringVar(&cfg.AdminToken, "admin-token", cfg.AdminToken, "bearer token for /admin/player/, /admin/room/ and /report-result")
+	fs.Var((*stringList)(&cfg.CORSOrigins), "cors-origins", "comma-separated origins allowed by CORS, or * for any")
+	fs.BoolVar(&cfg.PprofEnabled, "pprof-enabled", cfg.PprofEnabled, "expose /debug/pprof/")
+	fs.BoolVar(&cfg.BodyLoggingEnabled, "body-logging-enabled", cfg.BodyLoggingEnabled, "log bodies of failed requests")
+	fs.StringVar(&cfg.SMTPHost, "smtp-host", cfg.SMTPHost, "SMTP server for match emails, empty to disable")
+	fs.IntVar(&cfg.SMTPPort, "smtp-port", cfg.SMTPPort, "SMTP server port")
+	fs.StringVar(&cfg.SMTPUser, "smtp-user", cfg.SMTPUser, "SMTP user, also used as sender")
+	fs.StringVar(&cfg.SMTPPass, "smtp-pass", cfg.SMTPPass, "SMTP password")
+	fs.StringVar(&cfg.PublicURL, "public-url", cfg.PublicURL, "public base URL used in email links")
+	fs.StringVar(&cfg.EmailSecret, "email-secret", cfg.EmailSecret, "secret signing unsubscribe links, random per process if empty")
+}
+
+// loadConfig construye la configuración a partir de args (sin el nombre del programa).
+// Los flags se parsean dos veces: primero para conocer --config y después, sobre la
+// configuración ya cargada del fichero y del entorno, para que tengan la última palabra.
+func loadConfig(args []string) (*Config, error) {
+	cfg := defaultConfig()
+	var path string
+
+	fs := flag.NewFlagSet("diceball", flag.ContinueOnError)
+	fs.StringVar(&path, "config", "", "YAML config file")
+	bindFlags(fs, &cfg)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg = defaultConfig()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading config: %w", err)
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		// Un fichero vacío devuelve io.EOF: equivale a no cambiar nada
+		if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("parsing config %s: %w", path, err)
+		}
+	}
+
+	if err := applyEnv(&cfg); err != nil {
+		return nil, err
+	}
+
+	fs = flag.NewFlagSet("diceball", flag.ContinueOnError)
+	fs.StringVar(&path, "config", path, "YAML config file")
+	bindFlags(fs, &cfg)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// applyEnv sobrescribe cfg con las variables de entorno definidas. Cada campo acepta
+// DICEBALL_<NOMBRE> y, si existía, el nombre anterior sin prefijo.
+func applyEnv(cfg *Config) error {
+	return errors.Join(
+		envInt(&cfg.Port, "DICEBALL_PORT"),
+		envInt(&cfg.GRPCPort, "DICEBALL_GRPC_PORT"),
+		envString(&cfg.DatabasePath, "DICEBALL_DATABASE_PATH"),
+		envString(&cfg.LogLevel, "DICEBALL_LOG_LEVEL"),
+		envInt(&cfg.RoomSize, "DICEBALL_ROOM_SIZE"),
+		envInt(&cfg.ELOWindow, "DICEBALL_ELO_WINDOW"),
+		envInt(&cfg.ELOWindowStep, "DICEBALL_ELO_WINDOW_STEP"),
+		envDuration(&cfg.PlatformTimeout, "DICEBALL_PLATFORM_TIMEOUT", "PLATFORM_TIMEOUT"),
+		envDuration(&cfg.AwayTimeout, "DICEBALL_AWAY_TIMEOUT", "AWAY_TIMEOUT"),
+		envInt(&cfg.MaxPoolSize, "DICEBALL_MAX_POOL_SIZE"),
+		envDuration(&cfg.InviteExpiry, "DICEBALL_INVITE_EXPIRY"),
+		envDuration(&cfg.RoomTimeout, "DICEBALL_ROOM_TIMEOUT"),
+		envDuration(&cfg.CleanupInterval, "DICEBALL_CLEANUP_INTERVAL"),
+		envDuration(&cfg.QueueSnapshotInterval, "DICEBALL_QUEUE_SNAPSHOT_INTERVAL", "QUEUE_SNAPSHOT_INTERVAL"),
+		envInt(&cfg.DashboardRefreshMS, "DICEBALL_DASHBOARD_REFRESH_MS", "DASHBOARD_REFRESH_MS"),
+		envInt(&cfg.MaxGoroutines, "DICEBALL_MAX_GOROUTINES", "MAX_GOROUTINES"),
+		envDuration(&cfg.ReadTimeout, "DICEBALL_READ_TIMEOUT"),
+		envDuration(&cfg.WriteTimeout, "DICEBALL_WRITE_TIMEOUT"),
+		envDuration(&cfg.IdleTimeout, "DICEBALL_IDLE_TIMEOUT"),
+		envString(&cfg.AdminKey, "DICEBALL_ADMIN_KEY", "ADMIN_KEY"),
+		envString(&cfg.AdminToken, "DICEBALL_ADMIN_TOKEN", "ADMIN_TOKEN"),
+		envList(&cfg.CORSOrigins, "DICEBALL_CORS_ORIGINS", "CORS_ORIGINS"),
+		envBool(&cfg.PprofEnabled, "DICEBALL_PPROF_ENABLED", "PPROF_ENABLED"),
+		envBool(&cfg.BodyLoggingEnabled, "DICEBALL_BODY_LOGGING_ENABLED", "BODY_LOGGING_ENABLED"),
+		envString(&cfg.SMTPHost, "DICEBALL_SMTP_HOST", "SMTP_HOST"),
+		envInt(&cfg.SMTPPort, "DICEBALL_SMTP_PORT", "SMTP_PORT"),
+		envString(&cfg.SMTPUser, "DICEBALL_SMTP_USER", "SMTP_USER"),
+		envString(&cfg.SMTPPass, "DICEBALL_SMTP_PASS", "SMTP_PASS"),
+		envString(&cfg.PublicURL, "DICEBALL_PUBLIC_URL", "PUBLIC_URL"),
+		envString(&cfg.EmailSecret, "DICEBALL_EMAIL_SECRET", "EMAIL_SECRET"),
+	)
+}
+
+// lookupEnv devuelve la primera de names que esté definida.
+func lookupEnv(names ...string) (name, value string, ok bool) {
+	for _, name := range names {
+		if value, ok := os.LookupEnv(name); ok {
+			return name, value, true
+		}
+	}
+	return "", "", false
+}
+
+func envString(dst *string, names ...string) error {
+	if _, v, ok := lookupEnv(names...); ok {
+		*dst = v
+	}
+	return nil
+}
+
+func envList(dst *[]string, names ...string) error {
+	if _, v, ok := lookupEnv(names...); ok {
+		*dst = splitList(v)
+	}
+	return nil
+}
+
+func envInt(dst *int, names ...string) error {
+	name, v, ok := lookupEnv(names...)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("%s: invalid integer %q", name, v)
+	}
+	*dst = n
+	return nil
+}
+
+func envDuration(dst *time.Duration, names ...string) error {
+	name, v, ok := lookupEnv(names...)
+	if !ok {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("%s: invalid duration %q", name, v)
+	}
+	*dst = d
+	return nil
+}
+
+func envBool(dst *bool, names ...string) error {
+	name, v, ok := lookupEnv(names...)
+	if !ok {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("%s: invalid boolean %q", name, v)
+	}
+	*dst = b
+	return nil
+}
+
+// stringList es un flag con una lista separada por comas.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = splitList(v)
+	return nil
+}
+
+// splitList separa v por comas descartando espacios y elementos vacíos.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// validate comprueba que la configuración permite arrancar el servidor y describe
+// todos los valores inválidos a la vez.
+func (c *Config) validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Port > 0 && c.Port <= 65535, "port must be between 1 and 65535, got %d", c.Port)
+	check(c.GRPCPort >= 0 && c.GRPCPort <= 65535, "grpc_port must be between 0 and 65535, got %d", c.GRPCPort)
+	check(c.GRPCPort != c.Port, "grpc_port must differ from port, both are %d", c.Port)
+	check(c.DatabasePath != "", "database_path is required")
+	check(c.RoomSize >= 2, "room_size must be at least 2, got %d", c.RoomSize)
+	check(c.ELOWindow >= 0, "elo_window must not be negative, got %d", c.ELOWindow)
+	check(c.ELOWindowStep >= 0, "elo_window_step must not be negative, got %d", c.ELOWindowStep)
+	check(c.PlatformTimeout > 0, "platform_timeout must be positive, got %s", c.PlatformTimeout)
+	check(c.AwayTimeout > 0, "away_timeout must be positive, got %s", c.AwayTimeout)
+	check(c.MaxPoolSize > 0, "max_pool_size must be positive, got %d", c.MaxPoolSize)
+	check(c.InviteExpiry > 0, "invite_expiry must be positive, got %s", c.InviteExpiry)
+	check(c.RoomTimeout > 0, "room_timeout must be positive, got %s", c.RoomTimeout)
+	check(c.CleanupInterval > 0, "cleanup_interval must be positive, got %s", c.CleanupInterval)
+	check(c.QueueSnapshotInterval > 0, "queue_snapshot_interval must be positive, got %s", c.QueueSnapshotInterval)
+	check(c.DashboardRefreshMS >= 200 && c.DashboardRefreshMS <= 30000,
+		"dashboard_refresh_ms must be between 200 and 30000, got %d", c.DashboardRefreshMS)
+	check(c.MaxGoroutines > 0, "max_goroutines must be positive, got %d", c.MaxGoroutines)
+	for _, origin := range c.CORSOrigins {
+		check(origin == corsWildcard || strings.HasPrefix(origin, "http://") || strings.HasPrefix(origin, "https://"),
+			"cors_origins entries must be * or start with http:// or https://, got %q", origin)
+	}
+	check(c.SMTPPort > 0 && c.SMTPPort <= 65535, "smtp_port must be between 1 and 65535, got %d", c.SMTPPort)
+	check(strings.HasPrefix(c.PublicURL, "http://") || strings.HasPrefix(c.PublicURL, "https://"),
+		"public_url must start with http:// or https://, got %q", c.PublicURL)
+	check(c.ReadTimeout >= 0 && c.WriteTimeout >= 0 && c.IdleTimeout >= 0, "server timeouts must not be negative")
+
+	var lvl slog.Level
+	check(lvl.UnmarshalText([]byte(c.LogLevel)) == nil, "log_level must be debug, info, warn or error, got %q", c.LogLevel)
+
+	return errors.Join(errs...)
+}
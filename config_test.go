@@ -0,0 +1,124 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// configFixture devuelve la ruta de un fichero de testdata/config.
+func configFixture(name string) string {
+	return filepath.Join("testdata", "config", name)
+}
+
+func TestLoadConfigPartialFileKeepsDefaults(t *testing.T) {
+	cfg, err := loadConfig([]string{"--config", configFixture("partial.yaml")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := defaultConfig()
+	want.Port = 8181
+	want.RoomSize = 4
+	want.PlatformTimeout = 90 * time.Second
+	want.CORSOrigins = []string{"https://diceball.example"}
+	if !reflect.DeepEqual(*cfg, want) {
+		t.Fatalf("loaded %+v\nwant %+v", *cfg, want)
+	}
+}
+
+func TestLoadConfigEmptyAndExampleFiles(t *testing.T) {
+	// Ni un fichero vacío ni el ejemplo, que repite los valores por defecto, deben
+	// cambiar nada
+	for _, path := range []string{configFixture("empty.yaml"), "config.example.yaml"} {
+		cfg, err := loadConfig([]string{"--config", path})
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		want := defaultConfig()
+		// El ejemplo escribe cors_origins: [] y yaml lo decodifica como lista vacía
+		cfg.CORSOrigins, want.CORSOrigins = nil, nil
+		if !reflect.DeepEqual(*cfg, want) {
+			t.Errorf("%s: loaded %+v\nwant %+v", path, *cfg, want)
+		}
+	}
+}
+
+func TestLoadConfigReportsEveryInvalidField(t *testing.T) {
+	_, err := loadConfig([]string{"--config", configFixture("invalid.yaml")})
+	if err == nil {
+		t.Fatal("invalid config loaded without error")
+	}
+	for _, field := range []string{"port", "room_size", "dashboard_refresh_ms", "log_level"} {
+		if !strings.Contains(err.Error(), field+" must") {
+			t.Errorf("error does not mention %s: %v", field, err)
+		}
+	}
+}
+
+func TestLoadConfigRejectsUnknownFields(t *testing.T) {
+	_, err := loadConfig([]string{"--config", configFixture("unknown_field.yaml")})
+	if err == nil || !strings.Contains(err.Error(), "romo_size") {
+		t.Fatalf("got error %v, want one naming romo_size", err)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	if _, err := loadConfig([]string{"--config", configFixture("missing.yaml")}); err == nil {
+		t.Fatal("missing config file loaded without error")
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	// partial.yaml fija port, room_size y platform_timeout; el entorno pisa dos de
+	// ellos y un flag pisa uno de esos dos
+	t.Setenv("DICEBALL_PORT", "8282")
+	t.Setenv("PLATFORM_TIMEOUT", "2m")
+	t.Setenv("DICEBALL_ROOM_SIZE", "3")
+
+	cfg, err := loadConfig([]string{"--config", configFixture("partial.yaml"), "--room-size", "6"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 8282 {
+		t.Errorf("port = %d, want 8282 from env over file", cfg.Port)
+	}
+	if cfg.PlatformTimeout != 2*time.Minute {
+		t.Errorf("platform_timeout = %s, want 2m from the legacy env name", cfg.PlatformTimeout)
+	}
+	if cfg.RoomSize != 6 {
+		t.Errorf("room_size = %d, want 6 from flag over env", cfg.RoomSize)
+	}
+	if !slices.Equal(cfg.CORSOrigins, []string{"https://diceball.example"}) {
+		t.Errorf("cors_origins = %q, want the file value", cfg.CORSOrigins)
+	}
+}
+
+func TestLoadConfigInvalidEnv(t *testing.T) {
+	t.Setenv("DICEBALL_PORT", "eighty")
+	if _, err := loadConfig(nil); err == nil || !strings.Contains(err.Error(), "DICEBALL_PORT") {
+		t.Fatalf("got error %v, want one naming DICEBALL_PORT", err)
+	}
+}
+
+func TestDashboardRefreshOutOfRangeRejected(t *testing.T) {
+	for _, ms := range []int{0, 199, 30001} {
+		if _, err := loadConfig([]string{"--dashboard-refresh-ms", strconv.Itoa(ms)}); err == nil || !strings.Contains(err.Error(), "dashboard_refresh_ms") {
+			t.Errorf("dashboard_refresh_ms=%d: got error %v, want a range error", ms, err)
+		}
+	}
+	for _, ms := range []int{200, 30000} {
+		cfg, err := loadConfig([]string{"--dashboard-refresh-ms", strconv.Itoa(ms)})
+		if err != nil {
+			t.Fatalf("dashboard_refresh_ms=%d: %v", ms, err)
+		}
+		// Los extremos se aceptan tal cual, sin ajustarlos
+		if cfg.DashboardRefreshMS != ms {
+			t.Errorf("dashboard_refresh_ms=%d: loaded %d", ms, cfg.DashboardRefreshMS)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// corsWildcard en la lista de orígenes abre la API a cualquier origen, como antes de
+// existir la lista. Pensado para desarrollo local.
+const corsWildcard = "*"
+
+const (
+	corsAllowMethods = "GET, POST, DELETE, OPTIONS"
+	corsAllowHeaders = "Content-Type, Authorization, X-Admin-Key, X-Request-ID"
+)
+
+// originAllowed indica si origin puede llamar a la API desde el navegador.
+func originAllowed(origins []string, origin string) bool {
+	return slices.Contains(origins, corsWildcard) || slices.Contains(origins, origin)
+}
+
+// cors añade las cabeceras CORS a las peticiones cuyo Origin está en origins y
+// contesta directamente los preflight OPTIONS. A los orígenes no permitidos no se les
+// añade nada, así que el navegador bloquea la respuesta.
+func cors(origins []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && originAllowed(origins, origin) {
+			h := w.Header()
+			if slices.Contains(origins, corsWildcard) {
+				h.Set("Access-Control-Allow-Origin", corsWildcard)
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+				h.Add("Vary", "Origin")
+			}
+			h.Set("Access-Control-Allow-Methods", corsAllowMethods)
+			h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			h.Set("Access-Control-Expose-Headers", "X-Request-ID")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// wsCheckOrigin aplica a los websockets la misma lista que cors. Las conexiones del
+// propio host, o sin Origin, se aceptan siempre.
+func wsCheckOrigin(origins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || originAllowed(origins, origin) {
+			return true
+		}
+		_, host, _ := strings.Cut(origin, "://")
+		return strings.EqualFold(host, r.Host)
+	}
+}
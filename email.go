@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Email es un mensaje pendiente de envío.
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// EmailNotifier envía correos por SMTP de forma asíncrona a través de un canal con buffer.
+type EmailNotifier struct {
+	host    string
+	port    string
+	user    string
+	pass    string
+	baseURL string
+	secret  []byte
+	queue   chan Email
+}
+
+var (
+	emailOptIns = make(map[string]string)
+	emailMutex  sync.Mutex
+	// notifier lo crea run a partir de la configuración; nil si no hay SMTP.
+	notifier *EmailNotifier
+)
+
+// newEmailNotifier configura el notificador con los ajustes SMTP de cfg. Si SMTPHost
+// está vacío devuelve nil y no se envían correos.
+func newEmailNotifier(cfg *Config) *EmailNotifier {
+	if cfg.SMTPHost == "" {
+		return nil
+	}
+
+	// El secreto firma los enlaces de baja; si no se configura se genera uno por proceso.
+	secret := []byte(cfg.EmailSecret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+
+	return &EmailNotifier{
+		host:    cfg.SMTPHost,
+		port:    strconv.Itoa(cfg.SMTPPort),
+		user:    cfg.SMTPUser,
+		pass:    cfg.SMTPPass,
+		baseURL: strings.TrimRight(cfg.PublicURL, "/"),
+		secret:  secret,
+		queue:   make(chan Email, 100),
+	}
+}
+
+// run consume la cola de correos. Debe lanzarse en su propia goroutine.
+func (n *EmailNotifier) run() {
+	addr := n.host + ":" + n.port
+	var auth smtp.Auth
+	if n.user != "" {
+		auth = smtp.PlainAuth("", n.user, n.pass, n.host)
+	}
+
+	for e := range n.queue {
+		msg := "From: " + n.user + "\r\n" +
+			"To: " + e.To + "\r\n" +
+			"Subject: " + e.Subject + "\r\n" +
+			"Content-Type: text/plain; charset=UTF-8\r\n" +
+			"\r\n" + e.Body
+		if err := smtp.SendMail(addr, auth, n.user, []string{e.To}, []byte(msg)); err != nil {
+			slog.Error("sending email failed", "to", e.To, "error", err)
+		}
+	}
+}
+
+// unsubscribeToken firma el ID del jugador para que solo él pueda darse de baja.
+func (n *EmailNotifier) unsubscribeToken(playerID string) string {
+	mac := hmac.New(sha256.New, n.secret)
+	mac.Write([]byte(playerID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// notifyMatchFound encola el aviso de partida encontrada si el jugador se ha suscrito.
+// Nunca bloquea: si la cola está llena el correo se descarta.
+func (n *EmailNotifier) notifyMatchFound(playerID, roomID string) {
+	if n == nil {
+		return
+	}
+
+	emailMutex.Lock()
+	to, ok := emailOptIns[playerID]
+	emailMutex.Unlock()
+	if !ok {
+		return
+	}
+
+	unsubscribe := n.baseURL + "/unsubscribe?id=" + url.QueryEscape(playerID) + "&token=" + n.unsubscribeToken(playerID)
+	e := Email{
+		To:      to,
+		Subject: "Your match is ready!",
+		Body: "Your Diceball match is ready. Room: " + roomID + "\r\n\r\n" +
+			"To stop receiving these emails: " + unsubscribe + "\r\n",
+	}
+
+	select {
+	case n.queue <- e:
+	default:
+		slog.Warn("email queue full, dropping notification", "player_id", playerID)
+	}
+}
+
+// handleEmailOptIn atiende POST /player/{id}/email-opt-in con {"email": "..."}.
+func handleEmailOptIn(w http.ResponseWriter, r *http.Request, playerID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	addr, err := mail.ParseAddress(body.Email)
+	if err != nil {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+
+	state.mu.RLock()
+	_, exists := state.players[playerID]
+	state.mu.RUnlock()
+	if !exists {
+		http.Error(w, "Player not found", http.StatusNotFound)
+		return
+	}
+
+	emailMutex.Lock()
+	emailOptIns[playerID] = addr.Address
+	emailMutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "subscribed"})
+}
+
+func handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	playerID := query.Get("id")
+	token := query.Get("token")
+
+	if playerID == "" || token == "" {
+		http.Error(w, "ID and token are required", http.StatusBadRequest)
+		return
+	}
+
+	if notifier == nil || !hmac.Equal([]byte(token), []byte(notifier.unsubscribeToken(playerID))) {
+		http.Error(w, "Invalid token", http.StatusForbidden)
+		return
+	}
+
+	emailMutex.Lock()
+	delete(emailOptIns, playerID)
+	emailMutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "unsubscribed"})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// heartbeatInterval es la frecuencia de los eventos heartbeat, para que los proxies
+// no cierren la conexión por inactividad.
+const heartbeatInterval = 15 * time.Second
+
+// handleEvents atiende GET /events/{playerID} con Server-Sent Events: envía waiting al
+// conectar, heartbeat cada 15s y matched en cuanto matchPlayers empareja al jugador.
+func handleEvents(w http.ResponseWriter, r *http.Request) {
+	playerID := r.URL.Path[len("/events/"):]
+	if playerID == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	state.mu.RLock()
+	player, exists := state.players[playerID]
+	state.mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Player not found", http.StatusNotFound)
+		return
+	}
+
+	// El stream es de larga duración: anulamos el WriteTimeout del servidor si lo hay
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	writeEvent(w, "waiting", map[string]string{"playerID": playerID})
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case opponentIDs := <-player.OpponentIDs:
+			writeEvent(w, "matched", map[string]any{
+				"players":        opponentIDs,
+				"roomID":         player.RoomID,
+				"matchQuality":   player.MatchQuality,
+				"opponentName":   player.OpponentName,
+				"opponentAvatar": player.OpponentAvatar,
+			})
+			flusher.Flush()
+
+			state.mu.Lock()
+			markDelivered(player, opponentIDs)
+			state.mu.Unlock()
+			return
+		case <-player.Cancelled:
+			reason := takeCancelReason(playerID)
+
+			writeEvent(w, noticeStatus(reason), map[string]string{"reason": reason})
+			flusher.Flush()
+			return
+		case <-heartbeat.C:
+			// Una conexión abierta cuenta como latido del jugador
+			state.mu.Lock()
+			player.LastSeen = time.Now()
+			state.mu.Unlock()
+
+			writeEvent(w, "heartbeat", map[string]int64{"time": time.Now().Unix()})
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent escribe un evento SSE con data codificado como JSON.
+func writeEvent(w http.ResponseWriter, event string, data any) {
+	payload, _ := json.Marshal(data)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// exportHeader son las columnas de /export/stats.
+var exportHeader = []string{"room_id", "player1", "player2", "created_at", "ended_at", "duration_seconds"}
+
+// handleExportStats atiende GET /export/stats?since=<RFC3339> con una fila CSV por
+// partida del historial, de la más antigua a la más reciente. ended_at y
+// duration_seconds quedan vacíos mientras la partida sigue abierta.
+func handleExportStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid since, expected RFC3339", http.StatusBadRequest)
+			return
+		}
+		// created_at se guarda en hora local: comparamos en la misma zona
+		since = t.In(time.Local)
+	}
+
+	rows, err := db.QueryContext(r.Context(), `SELECT room_id, player1_id, player2_id, created_at, ended_at
+		FROM matches WHERE created_at >= ? ORDER BY created_at`, since)
+	if err != nil {
+		http.Error(w, "Could not read history", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	filename := "diceball_stats_" + time.Now().UTC().Format("20060102T150405Z") + ".csv"
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	out := csv.NewWriter(w)
+	out.Write(exportHeader)
+	for rows.Next() {
+		var roomID, player1, player2 string
+		var createdAt time.Time
+		var endedAt sql.NullTime
+		if err := rows.Scan(&roomID, &player1, &player2, &createdAt, &endedAt); err != nil {
+			// La cabecera ya se ha enviado: solo podemos cortar el fichero
+			slog.ErrorContext(r.Context(), "exporting stats failed", "error", err)
+			break
+		}
+
+		ended, duration := "", ""
+		if endedAt.Valid {
+			ended = endedAt.Time.UTC().Format(time.RFC3339)
+			duration = strconv.FormatInt(int64(endedAt.Time.Sub(createdAt).Seconds()), 10)
+		}
+		out.Write([]string{roomID, player1, player2, createdAt.UTC().Format(time.RFC3339), ended, duration})
+	}
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(r.Context(), "exporting stats failed", "error", err)
+	}
+	out.Flush()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exportStats llama a handleExportStats con la query dada y devuelve el cuerpo crudo.
+func exportStats(t *testing.T, query string) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	handleExportStats(w, httptest.NewRequest(http.MethodGet, "/export/stats?"+query, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("export: status %d: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Fatalf("Content-Type is %q", ct)
+	}
+	return w.Body.String()
+}
+
+func TestExportStatsCSV(t *testing.T) {
+	resetState(t)
+
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	recordMatchCreated("r1", []string{`ana, la "rápida"`, "bob"}, created)
+	recordMatchEnded("r1", created.Add(90*time.Second))
+	recordMatchCreated("r2", []string{"carla", "dani"}, created.Add(time.Minute))
+
+	body := exportStats(t, "")
+	// Los IDs con comas o comillas van entre comillas y con las comillas duplicadas
+	if !strings.Contains(body, `"ana, la ""rápida""",bob`) {
+		t.Fatalf("player with comma and quotes not escaped:\n%s", body)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d rows, want header and 2 matches:\n%s", len(records), body)
+	}
+	if !slices.Equal(records[0], exportHeader) {
+		t.Fatalf("header is %q, want %q", records[0], exportHeader)
+	}
+
+	want := []string{"r1", `ana, la "rápida"`, "bob", created.UTC().Format(time.RFC3339), created.Add(90 * time.Second).UTC().Format(time.RFC3339), "90"}
+	if !slices.Equal(records[1], want) {
+		t.Fatalf("finished match row is %q, want %q", records[1], want)
+	}
+	// La partida abierta deja vacíos ended_at y duration_seconds
+	if records[2][0] != "r2" || records[2][4] != "" || records[2][5] != "" {
+		t.Fatalf("open match row is %q", records[2])
+	}
+}
+
+func TestExportStatsSince(t *testing.T) {
+	resetState(t)
+
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	recordMatchCreated("old", []string{"a", "b"}, created)
+	recordMatchCreated("new", []string{"c", "d"}, created.Add(30*time.Minute))
+
+	records, err := csv.NewReader(strings.NewReader(exportStats(t, "since="+created.Add(time.Minute).UTC().Format(time.RFC3339)))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1][0] != "new" {
+		t.Fatalf("since kept %q, want only the new match", records)
+	}
+
+	w := httptest.NewRecorder()
+	handleExportStats(w, httptest.NewRequest(http.MethodGet, "/export/stats?since=yesterday", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid since: status %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nombre_del_modulo/testfixtures"
+)
+
+// fixtures son los escenarios de testdata/fixtures, cargados por TestMain.
+var fixtures []testfixtures.Fixture
+
+func TestMain(m *testing.M) {
+	// Los handlers registran cada petición: en los tests solo es ruido
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// Un escenario mal escrito para todo el binario antes de ejecutar ningún test
+	var err error
+	fixtures, err = testfixtures.LoadDir(filepath.Join("testdata", "fixtures"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// TestFixtures ejecuta cada escenario contra routes, con el state recién creado y la
+// configuración que indique el escenario. Los pasos match pasan el emparejador hasta
+// que no forma más salas.
+func TestFixtures(t *testing.T) {
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			resetState(t)
+
+			cfg := defaultConfig()
+			if f.Config != "" {
+				loaded, err := loadConfig([]string{"--config", f.Config})
+				if err != nil {
+					t.Fatal(err)
+				}
+				cfg = *loaded
+			}
+
+			testfixtures.Run(t, routes(&cfg), f, func() {
+				for matchRound(&cfg) != nil {
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative matchmakerpb/matchmaker.proto
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"nombre_del_modulo/matchmakerpb"
+)
+
+// MatchmakerServer implementa el servicio gRPC Matchmaker sobre el mismo estado que
+// los handlers HTTP: un jugador puede entrar por gRPC y emparejarse con otro de /join.
+type MatchmakerServer struct {
+	matchmakerpb.UnimplementedMatchmakerServer
+	cfg *Config
+}
+
+// newGRPCServer crea el servidor gRPC con el servicio Matchmaker registrado.
+func newGRPCServer(cfg *Config) *grpc.Server {
+	s := grpc.NewServer()
+	matchmakerpb.RegisterMatchmakerServer(s, &MatchmakerServer{cfg: cfg})
+	return s
+}
+
+// JoinQueue pone al jugador en cola con las mismas validaciones y límites que /join.
+func (s *MatchmakerServer) JoinQueue(ctx context.Context, req *matchmakerpb.JoinRequest) (*matchmakerpb.JoinResponse, error) {
+	playerID := req.GetPlayerId()
+	if playerID == "" {
+		return nil, status.Error(codes.InvalidArgument, "ID is required")
+	}
+
+	platform := req.GetPlatform()
+	if platform != "" && !slices.Contains(platforms, platform) {
+		return nil, status.Error(codes.InvalidArgument, "Invalid platform")
+	}
+
+	elo := defaultELO
+	if req.GetElo() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid elo")
+	} else if req.GetElo() > 0 {
+		elo = int(req.GetElo())
+	}
+
+	name, avatarURL := req.GetName(), req.GetAvatar()
+	if name != "" {
+		if err := validateName(name); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if avatarURL != "" {
+		if err := validateAvatar(avatarURL); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	state.mu.RLock()
+	banned := isBanned(playerID, time.Now())
+	state.mu.RUnlock()
+	if banned {
+		return nil, status.Error(codes.PermissionDenied, "Player is banned")
+	}
+	if draining.Load() {
+		return nil, status.Error(codes.Unavailable, "Server is draining")
+	}
+	if ok, delay := allowJoin(peerIP(ctx)); !ok {
+		return nil, status.Errorf(codes.ResourceExhausted, "Too many join requests, retry in %ds", int(math.Ceil(delay.Seconds())))
+	}
+	if poolFull(s.cfg.MaxPoolSize) {
+		return nil, status.Error(codes.Unavailable, "Matchmaking pool is full")
+	}
+
+	if !enqueuePlayer(newPlayer(playerID, platform, name, avatarURL, elo)) {
+		return nil, status.Error(codes.AlreadyExists, "player already in queue")
+	}
+	slog.InfoContext(ctx, "player joined", "player_id", playerID, "platform", platform, "elo", elo, "transport", "grpc")
+
+	return &matchmakerpb.JoinResponse{PlayerId: playerID, Status: "waiting"}, nil
+}
+
+// WatchStatus sigue al jugador igual que /events/{id}: waiting al conectar, heartbeat
+// cada heartbeatInterval y un último evento matched, cancelled o room_closed.
+func (s *MatchmakerServer) WatchStatus(req *matchmakerpb.StatusRequest, stream matchmakerpb.Matchmaker_WatchStatusServer) error {
+	playerID := req.GetPlayerId()
+	if playerID == "" {
+		return status.Error(codes.InvalidArgument, "ID is required")
+	}
+
+	state.mu.RLock()
+	player, exists := state.players[playerID]
+	state.mu.RUnlock()
+
+	if !exists {
+		return status.Error(codes.NotFound, "Player not found")
+	}
+
+	if err := stream.Send(&matchmakerpb.StatusEvent{Status: "waiting"}); err != nil {
+		return err
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case opponentIDs := <-player.OpponentIDs:
+			err := stream.Send(&matchmakerpb.StatusEvent{
+				Status:         "matched",
+				Players:        opponentIDs,
+				RoomId:         player.RoomID,
+				MatchQuality:   int32(player.MatchQuality),
+				OpponentName:   player.OpponentName,
+				OpponentAvatar: player.OpponentAvatar,
+			})
+			if err != nil {
+				// Devolvemos el emparejamiento para que otro transporte pueda recogerlo
+				player.OpponentIDs <- opponentIDs
+				return err
+			}
+
+			state.mu.Lock()
+			markDelivered(player, opponentIDs)
+			state.mu.Unlock()
+			return nil
+		case <-player.Cancelled:
+			reason := takeCancelReason(playerID)
+			return stream.Send(&matchmakerpb.StatusEvent{Status: noticeStatus(reason), Reason: reason})
+		case <-heartbeat.C:
+			// Un stream abierto cuenta como latido del jugador
+			state.mu.Lock()
+			player.LastSeen = time.Now()
+			state.mu.Unlock()
+
+			if err := stream.Send(&matchmakerpb.StatusEvent{Status: "heartbeat"}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Cancel saca al jugador de la cola, como /cancel.
+func (s *MatchmakerServer) Cancel(ctx context.Context, req *matchmakerpb.CancelRequest) (*matchmakerpb.CancelResponse, error) {
+	playerID := req.GetPlayerId()
+	if playerID == "" {
+		return nil, status.Error(codes.InvalidArgument, "ID is required")
+	}
+
+	cancelPlayer(ctx, playerID)
+	return &matchmakerpb.CancelResponse{Status: "cancelled"}, nil
+}
+
+// ReportResult informa del ganador de una sala, como /report-result. Exige el mismo
+// token de administración, en los metadatos "authorization: Bearer <token>".
+func (s *MatchmakerServer) ReportResult(ctx context.Context, req *matchmakerpb.ResultRequest) (*matchmakerpb.ResultResponse, error) {
+	if !validBearer(ctx, s.cfg.AdminToken) {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+
+	roomID, winner := req.GetRoomId(), req.GetWinner()
+	if roomID == "" || winner == "" {
+		return nil, status.Error(codes.InvalidArgument, "Room and winner are required")
+	}
+
+	losers, err := reportResult(ctx, roomID, winner)
+	switch {
+	case errors.Is(err, errRoomNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, errWinnerNotInRoom):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	return &matchmakerpb.ResultResponse{RoomId: roomID, Winner: winner, Losers: losers}, nil
+}
+
+// validBearer comprueba el token de los metadatos igual que requireAdminToken. Si
+// adminToken está vacío no acepta ninguno.
+func validBearer(ctx context.Context, adminToken string) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(v, "Bearer ")
+		if ok && adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// peerIP devuelve la IP del cliente gRPC, para compartir con /join el límite por IP.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"nombre_del_modulo/matchmakerpb"
+)
+
+// newTestMatchmaker arranca el servidor gRPC sobre bufconn y devuelve un cliente.
+func newTestMatchmaker(t *testing.T, cfg *Config) matchmakerpb.MatchmakerClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := newGRPCServer(cfg)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return matchmakerpb.NewMatchmakerClient(conn)
+}
+
+// recvUntil lee eventos del stream saltando waiting y heartbeat.
+func recvUntil(t *testing.T, stream matchmakerpb.Matchmaker_WatchStatusClient) *matchmakerpb.StatusEvent {
+	t.Helper()
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.GetStatus() != "waiting" && event.GetStatus() != "heartbeat" {
+			return event
+		}
+	}
+}
+
+// watching abre WatchStatus y espera al evento waiting, para saber que el stream ya
+// está escuchando.
+func watching(t *testing.T, ctx context.Context, mm matchmakerpb.MatchmakerClient, playerID string) matchmakerpb.Matchmaker_WatchStatusClient {
+	t.Helper()
+	stream, err := mm.WatchStatus(ctx, &matchmakerpb.StatusRequest{PlayerId: playerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event, err := stream.Recv(); err != nil || event.GetStatus() != "waiting" {
+		t.Fatalf("first event = %v, %v; want waiting", event, err)
+	}
+	return stream
+}
+
+func TestGRPCJoinQueueToMatch(t *testing.T) {
+	resetState(t)
+	cfg := defaultConfig()
+	mm := newTestMatchmaker(t, &cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, id := range []string{"p1_modo", "p2_modo"} {
+		resp, err := mm.JoinQueue(ctx, &matchmakerpb.JoinRequest{PlayerId: id, Name: id + " name"})
+		if err != nil {
+			t.Fatalf("join %s: %v", id, err)
+		}
+		if resp.GetStatus() != "waiting" {
+			t.Fatalf("join %s: status %q", id, resp.GetStatus())
+		}
+	}
+	_, err := mm.JoinQueue(ctx, &matchmakerpb.JoinRequest{PlayerId: "p1_modo"})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Errorf("duplicate join: %v, want AlreadyExists", err)
+	}
+
+	s1 := watching(t, ctx, mm, "p1_modo")
+	s2 := watching(t, ctx, mm, "p2_modo")
+
+	created := matchRound(&cfg)
+	if created == nil {
+		t.Fatal("no room created")
+	}
+
+	e1, e2 := recvUntil(t, s1), recvUntil(t, s2)
+	if e1.GetStatus() != "matched" || e2.GetStatus() != "matched" {
+		t.Fatalf("statuses = %q, %q; want matched", e1.GetStatus(), e2.GetStatus())
+	}
+	if !slices.Equal(e1.GetPlayers(), []string{"p2_modo"}) || !slices.Equal(e2.GetPlayers(), []string{"p1_modo"}) {
+		t.Errorf("players = %v / %v", e1.GetPlayers(), e2.GetPlayers())
+	}
+	if e1.GetRoomId() != created.RoomID || e2.GetRoomId() != created.RoomID {
+		t.Errorf("rooms = %q / %q, want %q", e1.GetRoomId(), e2.GetRoomId(), created.RoomID)
+	}
+	if e1.GetOpponentName() != "p2_modo name" {
+		t.Errorf("opponent name = %q", e1.GetOpponentName())
+	}
+
+	// El emparejamiento entregado por gRPC pasa a reconnecting igual que por HTTP
+	state.mu.RLock()
+	_, reconnecting := state.reconnecting["p1_modo"]
+	state.mu.RUnlock()
+	if !reconnecting {
+		t.Error("p1_modo not moved to reconnecting after delivery")
+	}
+}
+
+func TestGRPCCancel(t *testing.T) {
+	resetState(t)
+	cfg := defaultConfig()
+	mm := newTestMatchmaker(t, &cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := mm.JoinQueue(ctx, &matchmakerpb.JoinRequest{PlayerId: "p1_modo"}); err != nil {
+		t.Fatal(err)
+	}
+	stream := watching(t, ctx, mm, "p1_modo")
+
+	resp, err := mm.Cancel(ctx, &matchmakerpb.CancelRequest{PlayerId: "p1_modo"})
+	if err != nil || resp.GetStatus() != "cancelled" {
+		t.Fatalf("cancel = %v, %v", resp, err)
+	}
+	if event := recvUntil(t, stream); event.GetStatus() != "cancelled" || event.GetReason() != "cancelled" {
+		t.Errorf("event = %v, want cancelled", event)
+	}
+	if len(state.pool) != 0 {
+		t.Errorf("pool has %d players after cancel", len(state.pool))
+	}
+
+	// Los errores de un stream de servidor llegan en el primer Recv
+	again, err := mm.WatchStatus(ctx, &matchmakerpb.StatusRequest{PlayerId: "p1_modo"})
+	if err == nil {
+		_, err = again.Recv()
+	}
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("watch after cancel: %v, want NotFound", err)
+	}
+	if _, err := mm.Cancel(ctx, &matchmakerpb.CancelRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("cancel without ID: %v, want InvalidArgument", err)
+	}
+}
+
+func TestGRPCReportResultRequiresToken(t *testing.T) {
+	resetState(t)
+	cfg := defaultConfig()
+	cfg.AdminToken = "secret"
+	mm := newTestMatchmaker(t, &cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	room := newRoom("room1", []string{"a", "b"}, time.Now())
+	room.transition(RoomActive, time.Now())
+	state.rooms[room.ID] = room
+
+	req := &matchmakerpb.ResultRequest{RoomId: "room1", Winner: "a"}
+	if _, err := mm.ReportResult(ctx, req); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("report without token: %v, want Unauthenticated", err)
+	}
+
+	authed := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+	resp, err := mm.ReportResult(authed, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(resp.GetLosers(), []string{"b"}) {
+		t.Errorf("losers = %v", resp.GetLosers())
+	}
+	if _, err := mm.ReportResult(authed, req); status.Code(err) != codes.NotFound {
+		t.Errorf("second report: %v, want NotFound", err)
+	}
+}
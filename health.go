@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// matcherRunning se activa tras la primera vuelta de matchPlayers y se desactiva
+// cuando termina.
+var matcherRunning atomic.Bool
+
+// readyPoolRatio es la fracción de Config.MaxPoolSize a partir de la cual /readyz pide
+// al balanceador que deje de enviar jugadores a esta instancia.
+const readyPoolRatio = 0.9
+
+// handleHealthz es la sonda de liveness: si el proceso responde, está vivo.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleReadyz es la sonda de readiness: responde 503 hasta que el emparejador está
+// en marcha, mientras el pool supere readyPoolRatio de su capacidad y mientras se drena.
+func handleReadyz(w http.ResponseWriter, r *http.Request, cfg *Config) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !matcherRunning.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "starting"})
+		return
+	}
+
+	if draining.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "draining"})
+		return
+	}
+
+	state.mu.RLock()
+	depth := len(state.pool)
+	state.mu.RUnlock()
+
+	if float64(depth) > readyPoolRatio*float64(cfg.MaxPoolSize) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]any{"status": "overloaded", "pool_depth": depth})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// historyPageSize es el número de partidas por página en /history.
+const historyPageSize = 50
+
+// db guarda el historial de partidas. El estado en memoria sigue siendo la fuente de
+// verdad de las partidas en curso; SQLite solo se usa como registro de auditoría.
+var db *sql.DB
+
+// MatchRecord es una fila de la tabla matches.
+type MatchRecord struct {
+	RoomID    string     `json:"roomID"`
+	Player1ID string     `json:"player1ID"`
+	Player2ID string     `json:"player2ID"`
+	Players   []string   `json:"players"`
+	CreatedAt time.Time  `json:"createdAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+}
+
+// openHistory abre la base de datos en path y crea la tabla matches si no existe.
+func openHistory(path string) (*sql.DB, error) {
+	conn, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+
+	// player1_id y player2_id se mantienen para consultas simples; players guarda la
+	// lista completa en JSON para salas de más de dos jugadores.
+	_, err = conn.Exec(`CREATE TABLE IF NOT EXISTS matches (
+		room_id    TEXT PRIMARY KEY,
+		player1_id TEXT NOT NULL,
+		player2_id TEXT NOT NULL,
+		players    TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		ended_at   DATETIME
+	)`)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("create matches table: %w", err)
+	}
+	return conn, nil
+}
+
+// recordMatchCreated inserta la sala recién creada. No debe llamarse con state.mu tomado.
+func recordMatchCreated(roomID string, ids []string, createdAt time.Time) {
+	players, _ := json.Marshal(ids)
+	_, err := db.Exec(`INSERT INTO matches (room_id, player1_id, player2_id, players, created_at)
+		VALUES (?, ?, ?, ?, ?)`, roomID, ids[0], ids[1], string(players), createdAt)
+	if err != nil {
+		slog.Error("recording match failed", "room_id", roomID, "error", err)
+	}
+}
+
+// recordMatchEnded marca la sala como terminada. No debe llamarse con state.mu tomado.
+func recordMatchEnded(roomID string, endedAt time.Time) {
+	_, err := db.Exec(`UPDATE matches SET ended_at = ? WHERE room_id = ? AND ended_at IS NULL`, endedAt, roomID)
+	if err != nil {
+		slog.Error("closing match failed", "room_id", roomID, "error", err)
+	}
+}
+
+// handleHistory atiende GET /history?page=N con las partidas más recientes primero.
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	page := 1
+	if v := r.URL.Query().Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid page", http.StatusBadRequest)
+			return
+		}
+		page = n
+	}
+
+	rows, err := db.QueryContext(r.Context(), `SELECT room_id, player1_id, player2_id, players, created_at, ended_at
+		FROM matches ORDER BY created_at DESC LIMIT ? OFFSET ?`,
+		historyPageSize, (page-1)*historyPageSize)
+	if err != nil {
+		http.Error(w, "Could not read history", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	matches := make([]MatchRecord, 0)
+	for rows.Next() {
+		var m MatchRecord
+		var players string
+		var endedAt sql.NullTime
+		if err := rows.Scan(&m.RoomID, &m.Player1ID, &m.Player2ID, &players, &m.CreatedAt, &endedAt); err != nil {
+			http.Error(w, "Could not read history", http.StatusInternalServerError)
+			return
+		}
+		json.Unmarshal([]byte(players), &m.Players)
+		if endedAt.Valid {
+			m.EndedAt = &endedAt.Time
+		}
+		matches = append(matches, m)
+	}
+	if rows.Err() != nil {
+		http.Error(w, "Could not read history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"page":    page,
+		"matches": matches,
+	})
+}
+
+// anonymizeHistory sustituye playerID por anonID en todas las partidas registradas.
+func anonymizeHistory(ctx context.Context, playerID, anonID string) error {
+	oldJSON, _ := json.Marshal(playerID)
+	newJSON, _ := json.Marshal(anonID)
+	_, err := db.ExecContext(ctx, `UPDATE matches SET
+		player1_id = CASE WHEN player1_id = ? THEN ? ELSE player1_id END,
+		player2_id = CASE WHEN player2_id = ? THEN ? ELSE player2_id END,
+		players    = replace(players, ?, ?)
+		WHERE player1_id = ? OR player2_id = ? OR instr(players, ?) > 0`,
+		playerID, anonID, playerID, anonID, string(oldJSON), string(newJSON),
+		playerID, playerID, string(oldJSON))
+	return err
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 100
+)
+
+// LeaderboardEntry es el balance de resultados de un jugador.
+type LeaderboardEntry struct {
+	PlayerID    string `json:"playerID"`
+	Wins        int    `json:"wins"`
+	Losses      int    `json:"losses"`
+	GamesPlayed int    `json:"gamesPlayed"`
+}
+
+// Leaderboard acumula en memoria los resultados informados con /report-result. Tiene
+// su propio lock y nunca se toma mientras se tiene state.mu.
+type Leaderboard struct {
+	mu      sync.RWMutex
+	entries map[string]*LeaderboardEntry
+}
+
+var leaderboard = &Leaderboard{entries: make(map[string]*LeaderboardEntry)}
+
+// entry devuelve el registro de playerID, creándolo si no existe. Requiere lb.mu.
+func (lb *Leaderboard) entry(playerID string) *LeaderboardEntry {
+	e, ok := lb.entries[playerID]
+	if !ok {
+		e = &LeaderboardEntry{PlayerID: playerID}
+		lb.entries[playerID] = e
+	}
+	return e
+}
+
+// record suma una victoria a winner y una derrota a cada uno de losers.
+func (lb *Leaderboard) record(winner string, losers []string) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	w := lb.entry(winner)
+	w.Wins++
+	w.GamesPlayed++
+	for _, id := range losers {
+		l := lb.entry(id)
+		l.Losses++
+		l.GamesPlayed++
+	}
+}
+
+// top devuelve una copia de los limit mejores jugadores: más victorias primero y, a
+// igualdad, menos partidas jugadas.
+func (lb *Leaderboard) top(limit int) []LeaderboardEntry {
+	lb.mu.RLock()
+	entries := make([]LeaderboardEntry, 0, len(lb.entries))
+	for _, e := range lb.entries {
+		entries = append(entries, *e)
+	}
+	lb.mu.RUnlock()
+
+	slices.SortFunc(entries, func(a, b LeaderboardEntry) int {
+		if a.Wins != b.Wins {
+			return b.Wins - a.Wins
+		}
+		if a.GamesPlayed != b.GamesPlayed {
+			return a.GamesPlayed - b.GamesPlayed
+		}
+		return strings.Compare(a.PlayerID, b.PlayerID)
+	})
+	return entries[:min(limit, len(entries))]
+}
+
+// rename traslada el registro de playerID a newID, para la anonimización.
+func (lb *Leaderboard) rename(playerID, newID string) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if e, ok := lb.entries[playerID]; ok {
+		delete(lb.entries, playerID)
+		e.PlayerID = newID
+		lb.entries[newID] = e
+	}
+}
+
+var (
+	errRoomNotFound    = errors.New("Room not found")
+	errWinnerNotInRoom = errors.New("Winner is not in this room")
+)
+
+// reportResult suma una victoria a winner y una derrota al resto de la sala, y cierra
+// la sala. Devuelve los perdedores. Si la sala no admite el cierre devuelve el error
+// de la transición.
+func reportResult(ctx context.Context, roomID, winner string) ([]string, error) {
+	now := time.Now()
+	state.mu.Lock()
+	room, exists := state.rooms[roomID]
+	if !exists {
+		state.mu.Unlock()
+		return nil, errRoomNotFound
+	}
+	if !slices.Contains(room.Players, winner) {
+		state.mu.Unlock()
+		return nil, errWinnerNotInRoom
+	}
+	if err := room.transition(RoomExpired, now); err != nil {
+		state.mu.Unlock()
+		return nil, err
+	}
+	delete(state.rooms, roomID)
+	losers := coPlayers(room.Players, winner)
+	state.mu.Unlock()
+
+	leaderboard.record(winner, losers)
+	slog.InfoContext(ctx, "result reported", "room_id", roomID, "winner", winner)
+	recordMatchEnded(roomID, now)
+	return losers, nil
+}
+
+// handleReportResult atiende POST /report-result?room=<roomID>&winner=<playerID>.
+// Suma una victoria al ganador y una derrota al resto de la sala, y cierra la sala.
+func handleReportResult(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	roomID := query.Get("room")
+	winner := query.Get("winner")
+	if roomID == "" || winner == "" {
+		http.Error(w, "Room and winner are required", http.StatusBadRequest)
+		return
+	}
+
+	losers, err := reportResult(r.Context(), roomID, winner)
+	switch {
+	case errors.Is(err, errRoomNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, errWinnerNotInRoom):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"roomID": roomID,
+		"winner": winner,
+		"losers": losers,
+	})
+}
+
+// handleLeaderboard atiende GET /leaderboard?limit=N.
+func handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	limit := defaultLeaderboardLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLeaderboardLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"players": leaderboard.top(limit),
+	})
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// setupLogger instala como logger por defecto un slog.JSONHandler sobre stderr con el
+// nivel indicado en --log-level: debug, info, warn o error. Los registros con contexto
+// de petición llevan además request_id.
+func setupLogger(level string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+	handler := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: lvl})
+	slog.SetDefault(slog.New(requestIDHandler{handler}))
+	return nil
+}
@@ -1,181 +1,431 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"html/template"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
 )
 
 type Player struct {
-	ID         string
-	Matched    bool
-	CreatedAt  time.Time
-	OpponentID chan string
-	RoomID     string
+	ID          string
+	Matched     bool
+	CreatedAt   time.Time
+	OpponentIDs chan []string // compañeros de sala, se envía una vez al emparejar
+	Cancelled   chan struct{} // se cierra si el jugador sale de la cola sin emparejar
+	RoomID      string
+	Platform    string
+	ELO         int
+	// MatchQuality es la diferencia de ELO entre el mejor y el peor jugador de la sala.
+	// Se fija al emparejar, antes de enviar por OpponentIDs.
+	MatchQuality int
+	// Name y AvatarURL son opcionales y se indican en /join. OpponentName y
+	// OpponentAvatar son los del rival y se fijan al emparejar; ver setOpponentProfiles.
+	Name           string
+	AvatarURL      string
+	OpponentName   string
+	OpponentAvatar string
+	LastSeen       time.Time
+	Conn           *wsConn // websocket del jugador, si está conectado por /ws/
+	IsAway         bool
+	AwaySince      time.Time
+	// Private indica que el jugador espera en una sala privada y no está en el pool.
+	Private bool
+	// ReconnectDeadline y ReconnectData se fijan al entregar el emparejamiento; ver
+	// markDelivered.
+	ReconnectDeadline time.Time
+	ReconnectData     *ReconnectData
+}
+
+// waitingEntry es un jugador en cola junto con el color de su indicador de actividad.
+type waitingEntry struct {
+	*Player
+	Indicator string
+}
+
+// activityIndicator devuelve la clase CSS según el tiempo desde el último latido:
+// verde por debajo de 10s, amarillo hasta 30s y rojo a partir de ahí.
+func activityIndicator(lastSeen time.Time) string {
+	switch since := time.Since(lastSeen); {
+	case since < 10*time.Second:
+		return "bg-green-500"
+	case since <= 30*time.Second:
+		return "bg-yellow-500"
+	default:
+		return "bg-red-500"
+	}
 }
 
 type ServerStats struct {
-	TotalPlayers   int
-	WaitingPlayers int
-	MatchedPlayers int
-	ActiveRooms    int
-}
-
-var (
-	players   = make(map[string]*Player)
-	rooms     = make(map[string][]string)
-	pool      []*Player
-	poolMutex sync.Mutex
-	roomMutex sync.Mutex
-)
+	TotalPlayers   int            `json:"totalPlayers"`
+	WaitingPlayers int            `json:"waitingPlayers"`
+	AwayPlayers    int            `json:"awayPlayers"`
+	MatchedPlayers int            `json:"matchedPlayers"`
+	ActiveRooms    int            `json:"activeRooms"`
+	Spectators     int            `json:"spectators"`
+	PlatformPools  map[string]int `json:"platformPools"`
+	AvgWaitSeconds float64        `json:"avgWaitSeconds"`
+	MaxWaitSeconds float64        `json:"maxWaitSeconds"`
+}
+
+// platforms son las etiquetas aceptadas en /join?platform=.
+var platforms = []string{"mobile", "desktop", "console"}
+
+// defaultELO es el ELO de los jugadores que no indican ?elo= en /join.
+const defaultELO = 1200
+
+// eloWindowInterval es cada cuánto crece la ventana de ELO en Config.ELOWindowStep.
+const eloWindowInterval = 10 * time.Second
+
+// PoolEntry es un jugador en cola con su número de llegada.
+type PoolEntry struct {
+	Seq    uint64
+	Player *Player
+}
+
+// poolSeq genera los números de secuencia de PoolEntry. Es monótono: cada /join obtiene
+// un número mayor que todos los anteriores.
+var poolSeq atomic.Uint64
+
+// serverState agrupa todo el estado compartido del matchmaking bajo un único lock,
+// de modo que no hay orden de adquisición que respetar entre varios mutex.
+//
+// Orden del pool: state.pool está siempre ordenado por PoolEntry.Seq, es decir, en
+// orden FIFO de llegada a /join, aunque haya altas y bajas concurrentes. Las bajas
+// (cancelación, emparejamiento, ausencia caducada) conservan el orden relativo del
+// resto, y un jugador ausente mantiene su entrada y por tanto su posición.
+// matchPlayers recorre el pool en ese orden. byELO tiene las mismas entradas ordenadas
+// por ELO, y a igual ELO por Seq, para buscar rivales con sort.Search; se mantiene en
+// cada alta y baja junto con pool.
+type serverState struct {
+	mu      sync.RWMutex
+	players map[string]*Player
+	rooms   map[string]*Room
+	pool    []*PoolEntry
+	byELO   []*PoolEntry
+
+	// privateRooms son las salas creadas con /create-room, por código de invitación.
+	privateRooms map[string]*privateRoom
+
+	// reconnecting guarda a los jugadores que ya recibieron su sala, durante
+	// reconnectWindow, para /reconnect.
+	reconnecting map[string]*Player
+
+	// bans guarda hasta cuándo está baneado cada jugador.
+	bans map[string]time.Time
+
+	// blocks guarda, por jugador, los jugadores con los que no quiere emparejarse.
+	// Los slices no se modifican en sitio: se sustituyen.
+	blocks map[string][]string
+
+	// tournaments son los torneos de eliminación directa, por ID.
+	tournaments map[string]*Tournament
+
+	// cancelNotices guarda el motivo por el que se retiró a un jugador de la cola desde
+	// fuera, para que su siguiente /status lo informe.
+	cancelNotices map[string]cancelNotice
+}
+
+var state = newServerState()
+
+func newServerState() *serverState {
+	return &serverState{
+		players:       make(map[string]*Player),
+		rooms:         make(map[string]*Room),
+		cancelNotices: make(map[string]cancelNotice),
+		privateRooms:  make(map[string]*privateRoom),
+		reconnecting:  make(map[string]*Player),
+		blocks:        make(map[string][]string),
+		bans:          make(map[string]time.Time),
+		tournaments:   make(map[string]*Tournament),
+	}
+}
 
 func main() {
-	http.HandleFunc("/", dashboardHandler)
-	http.HandleFunc("/join", handleJoin)
-	http.HandleFunc("/status/", handleStatus)
-	http.HandleFunc("/stats", statsHandler)
-	http.HandleFunc("/cancel", handleCancel)
-	go matchPlayers()
-	go cleanupOldRooms()
-
-	fmt.Println("Server running on :8080")
-	http.ListenAndServe(":8080", nil)
-}
-
-func dashboardHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.New("dashboard").Parse(`
-	<!DOCTYPE html>
-	<html lang="en">
-	<head>
-		<meta charset="UTF-8">
-		<meta name="viewport" content="width=device-width, initial-scale=1.0">
-		<title>Server Dashboard</title>
-		<script src="https://unpkg.com/htmx.org@1.9.6"></script>
-		<link href="https://cdn.jsdelivr.net/npm/tailwindcss@2.2.19/dist/tailwind.min.css" rel="stylesheet">
-	</head>
-	<body class="bg-gray-100">
-		<div class="container mx-auto px-4 py-8">
-			<h1 class="text-3xl font-bold mb-8 text-gray-800">Servidor Diceball</h1>
-			
-			<div id="stats" hx-get="/stats" hx-trigger="every 1s" class="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-4 gap-4 mb-8">
-				<!-- Stats will be updated here -->
-			</div>
-		</div>
-	</body>
-	</html>
-	`))
-
-	w.Header().Set("Content-Type", "text/html")
-	tmpl.Execute(w, nil)
+	cfg, err := loadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid configuration:", err)
+		os.Exit(2)
+	}
+	if err := setupLogger(cfg.LogLevel); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := run(cfg); err != nil {
+		slog.Error("server failed", "error", err)
+		os.Exit(1)
+	}
 }
 
-func statsHandler(w http.ResponseWriter, r *http.Request) {
-	statsTemplate := template.Must(template.New("stats").Parse(`
-	<div class="bg-white rounded-lg shadow p-4">
-		<div class="grid grid-cols-4 gap-4 mb-4">
-			<div class="text-center p-2 bg-blue-50 rounded">
-				<p class="text-sm text-blue-600">Total Jugadpres</p>
-				<p class="text-xl font-bold">{{.TotalPlayers}}</p>
-			</div>
-			<div class="text-center p-2 bg-yellow-50 rounded">
-				<p class="text-sm text-yellow-600">En Cola</p>
-				<p class="text-xl font-bold">{{.WaitingPlayers}}</p>
-			</div>
-			<div class="text-center p-2 bg-green-50 rounded">
-				<p class="text-sm text-green-600">Jugando</p>
-				<p class="text-xl font-bold">{{.MatchedPlayers}}</p>
-			</div>
-			<div class="text-center p-2 bg-purple-50 rounded">
-				<p class="text-sm text-purple-600">Salas Creadas</p>
-				<p class="text-xl font-bold">{{.ActiveRooms}}</p>
-			</div>
-		</div>
-
-		<div class="grid grid-cols-1 md:grid-cols-2 gap-6">
-			<div class="bg-white rounded-lg shadow p-6">
-				<h2 class="text-xl font-semibold mb-4 text-gray-700">Jugadores en Cola ({{.WaitingPlayers}})</h2>
-				<div class="space-y-2">
-					{{range .WaitingPlayersList}}
-					<div class="flex items-center justify-between p-3 bg-gray-50 rounded">
-						<span class="font-mono text-sm">{{.ID}}</span>
-						<span class="text-xs text-gray-500">{{.CreatedAt.Format "15:04:05"}}</span>
-					</div>
-					{{else}}
-					<div class="p-3 text-center text-gray-500">No hay jugadores</div>
-					{{end}}
-				</div>
-			</div>
-			
-			<div class="bg-white rounded-lg shadow p-6">
-				<h2 class="text-xl font-semibold mb-4 text-gray-700">Salas Activas ({{.ActiveRooms}})</h2>
-				<div class="space-y-2">
-					{{range $room, $players := .ActiveRoomsList}}
-					<div class="p-3 bg-gray-50 rounded">
-						<div class="font-medium text-gray-600 mb-2">Room: {{$room}}</div>
-						<div class="flex justify-between text-sm">
-							<span>{{index $players 0}}</span>
-							<span class="text-gray-500">vs</span>
-							<span>{{index $players 1}}</span>
-						</div>
-					</div>
-					{{else}}
-					<div class="p-3 text-center text-gray-500">No hay salas</div>
-					{{end}}
-				</div>
-			</div>
-		</div>
-	</div>
-	`))
+// routes registra todos los endpoints HTTP en un mux nuevo.
+func routes(cfg *Config) *http.ServeMux {
+	// Usamos un mux propio: importar net/http/pprof registra sus handlers en
+	// http.DefaultServeMux sin autenticación.
+	mux := http.NewServeMux()
+	handleRoute(mux, "/", "dashboard", func(w http.ResponseWriter, r *http.Request) { dashboardHandler(w, r, cfg) })
+	handleRoute(mux, "/join", "join", func(w http.ResponseWriter, r *http.Request) { handleJoin(w, r, cfg) })
+	handleRoute(mux, "/status/", "status", handleStatus)
+	handleRoute(mux, "/events/", "events", handleEvents)
+	handleRoute(mux, "/ws/", "ws", handleWebSocket)
+	handleRoute(mux, "/stats", "stats", statsHandler)
+	handleRoute(mux, "/history", "history", handleHistory)
+	handleRoute(mux, "/export/stats", "export_stats", handleExportStats)
+	handleRoute(mux, "/create-room", "create_room", func(w http.ResponseWriter, r *http.Request) { handleCreateRoom(w, r, cfg) })
+	handleRoute(mux, "/join-private", "join_private", handleJoinPrivate)
+	handleRoute(mux, "/invite/", "invite", handleInvite)
+	handleRoute(mux, "/player-wait/", "player_wait", handlePlayerWait)
+	handleRoute(mux, "/reconnect", "reconnect", handleReconnect)
+	handleRoute(mux, "/cancel", "cancel", handleCancel)
+	handleRoute(mux, "/player/", "player", handlePlayer)
+	handleRoute(mux, "/room/", "room", handleRoom)
+	handleRoute(mux, "/roll", "roll", handleRoll)
+	handleRoute(mux, "/spectate/", "spectate", handleSpectate)
+	handleRoute(mux, "/unsubscribe", "unsubscribe", handleUnsubscribe)
+	handleRoute(mux, "/admin/queue-snapshots", "admin_queue_snapshots", requireAdminKey(cfg.AdminKey, handleQueueSnapshots))
+	handleRoute(mux, "/admin/pool/flush", "admin_pool_flush", requireAdminKey(cfg.AdminKey, handlePoolFlush))
+	handleRoute(mux, "/admin/pool/drain", "admin_pool_drain", requireAdminKey(cfg.AdminKey, handlePoolDrain))
+	handleRoute(mux, "/admin/players", "admin_players_list", requireAdminKey(cfg.AdminKey, handleListPlayers))
+	handleRoute(mux, "/admin/players/", "admin_players", requireAdminKey(cfg.AdminKey, handleAdminPlayers))
+	handleRoute(mux, "/admin/rooms", "admin_rooms", requireAdminKey(cfg.AdminKey, handleListRooms))
+	handleRoute(mux, "/admin/announce", "admin_announce", requireAdminKey(cfg.AdminKey, handleAnnounce))
+	handleRoute(mux, "/announcement", "announcement", handleAnnouncement)
+	handleRoute(mux, "/admin/player/", "admin_player", requireAdminToken(cfg.AdminToken, handleDeletePlayer))
+	handleRoute(mux, "/admin/room/", "admin_room", requireAdminToken(cfg.AdminToken, handleDeleteRoom))
+	handleRoute(mux, "/report-result", "report_result", requireAdminToken(cfg.AdminToken, handleReportResult))
+	handleRoute(mux, "/leaderboard", "leaderboard", handleLeaderboard)
+	handleRoute(mux, "/tournament/", "tournament", func(w http.ResponseWriter, r *http.Request) { handleTournament(w, r, cfg) })
+	handleRoute(mux, "/healthz", "healthz", handleHealthz)
+	handleRoute(mux, "/readyz", "readyz", func(w http.ResponseWriter, r *http.Request) { handleReadyz(w, r, cfg) })
+	mux.Handle("/metrics", promhttp.Handler())
+	if cfg.PprofEnabled {
+		registerPprof(mux, cfg.AdminKey)
+	}
+	return mux
+}
 
+// run arranca los transportes y las tareas de fondo y bloquea hasta SIGINT/SIGTERM o
+// hasta que un servidor falla. Devuelve error si algo no arranca, si un servidor se
+// cae o si el apagado no termina a tiempo.
+func run(cfg *Config) error {
+	var err error
+	db, err = openHistory(cfg.DatabasePath)
+	if err != nil {
+		return fmt.Errorf("opening match history: %w", err)
+	}
+	defer db.Close()
+
+	mux := routes(cfg)
+
+	// ctx se cancela con SIGINT/SIGTERM y detiene todas las goroutines de fondo
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// notifier se fija antes de lanzar nada que pueda leerlo
+	notifier = newEmailNotifier(cfg)
+	go matchPlayers(ctx, cfg)
+	if notifier != nil {
+		go notifier.run()
+	}
+	go cleanupOldRooms(ctx, cfg.CleanupInterval, cfg.RoomTimeout)
+	go captureQueueSnapshots(ctx, cfg.QueueSnapshotInterval)
+	go cleanupJoinLimiters(ctx)
+
+	upgrader.CheckOrigin = wsCheckOrigin(cfg.CORSOrigins)
+
+	var handler http.Handler = mux
+	if cfg.BodyLoggingEnabled {
+		handler = BodyLoggingMiddleware(handler)
+	}
+
+	server := &http.Server{
+		Addr:         ":" + strconv.Itoa(cfg.Port),
+		Handler:      requestID(securityHeaders(cors(cfg.CORSOrigins, loadShedding(cfg.MaxGoroutines, requestTimeout(handler))))),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+
+	// Abrimos los dos puertos antes de servir: si uno está ocupado no llega a arrancar
+	// ninguno de los dos transportes
+	lis, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return fmt.Errorf("http listen: %w", err)
+	}
+	var grpcLis net.Listener
+	if cfg.GRPCPort > 0 {
+		grpcLis, err = net.Listen("tcp", ":"+strconv.Itoa(cfg.GRPCPort))
+		if err != nil {
+			lis.Close()
+			return fmt.Errorf("grpc listen: %w", err)
+		}
+	}
+
+	serverErr := make(chan error, 2)
+	go func() {
+		serverErr <- fmt.Errorf("http server: %w", server.Serve(lis))
+	}()
+	slog.Info("server running", "addr", server.Addr)
+
+	var grpcServer *grpc.Server
+	if grpcLis != nil {
+		grpcServer = newGRPCServer(cfg)
+		go func() {
+			serverErr <- fmt.Errorf("grpc server: %w", grpcServer.Serve(grpcLis))
+		}()
+		slog.Info("grpc server running", "addr", grpcLis.Addr().String())
+	}
+
+	// Si un servidor se cae apagamos también el otro y devolvemos su error
+	var runErr error
+	select {
+	case runErr = <-serverErr:
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Avisamos a los jugadores en cola y a los espectadores antes de cerrar: las
+	// conexiones SSE y websocket reciben el motivo y terminan, lo que permite que
+	// Shutdown no espere por ellas.
+	flushed := flushPool("server_shutdown") + flushPrivateHosts("server_shutdown")
+	spectators := closeSpectators()
+	slog.Info("shutting down", "players_notified", flushed, "spectators_closed", spectators)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if grpcServer != nil {
+		// GracefulStop espera a que terminen los streams; si se agota el plazo los cortamos
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			grpcServer.Stop()
+		}
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return errors.Join(runErr, fmt.Errorf("shutdown: %w", err))
+	}
+	return runErr
+}
+
+func dashboardHandler(w http.ResponseWriter, r *http.Request, cfg *Config) {
+	data := struct {
+		RefreshMS int
+	}{
+		RefreshMS: cfg.DashboardRefreshMS,
+	}
+
+	renderTemplate(w, r, "index.html", data)
+}
+
+func statsHandler(w http.ResponseWriter, r *http.Request) {
 	// Obtener datos de forma segura
-	poolMutex.Lock()
-	roomMutex.Lock()
+	state.mu.RLock()
 
 	stats := ServerStats{
-		TotalPlayers:   len(players),
-		WaitingPlayers: len(pool),
-		MatchedPlayers: len(players) - len(pool),
-		ActiveRooms:    len(rooms),
+		TotalPlayers:   len(state.players),
+		WaitingPlayers: len(state.pool),
+		MatchedPlayers: len(state.players) - len(state.pool),
+		PlatformPools:  make(map[string]int),
+	}
+
+	for _, e := range state.pool {
+		if e.Player.Platform != "" {
+			stats.PlatformPools[e.Player.Platform]++
+		}
 	}
 
-	waitingPlayers := make([]*Player, 0)
-	for _, p := range players {
-		if !p.Matched {
-			waitingPlayers = append(waitingPlayers, p)
+	waitingPlayers := make([]waitingEntry, 0)
+	awayPlayers := make([]*Player, 0)
+	privateWaiting := 0
+	for _, p := range state.players {
+		switch {
+		case p.Matched:
+		case p.Private:
+			privateWaiting++
+		case p.IsAway:
+			awayPlayers = append(awayPlayers, p)
+		default:
+			waitingPlayers = append(waitingPlayers, waitingEntry{Player: p, Indicator: activityIndicator(p.LastSeen)})
 		}
 	}
+	stats.AwayPlayers = len(awayPlayers)
+
+	// La espera se calcula solo sobre los jugadores activos en cola
+	now := time.Now()
+	for _, p := range waitingPlayers {
+		wait := now.Sub(p.CreatedAt).Seconds()
+		stats.AvgWaitSeconds += wait
+		stats.MaxWaitSeconds = max(stats.MaxWaitSeconds, wait)
+	}
+	if len(waitingPlayers) > 0 {
+		stats.AvgWaitSeconds /= float64(len(waitingPlayers))
+	}
+	stats.WaitingPlayers -= len(awayPlayers)
+	// Los anfitriones de salas privadas no están en el pool ni han jugado aún
+	stats.MatchedPlayers -= privateWaiting
 
 	roomsCopy := make(map[string][]string)
-	for k, v := range rooms {
-		roomsCopy[k] = v
+	for id, room := range state.rooms {
+		if room.State == RoomActive {
+			roomsCopy[id] = room.Players
+		}
+		stats.Spectators += len(room.spectators)
 	}
+	stats.ActiveRooms = len(roomsCopy)
 
-	roomMutex.Unlock()
-	poolMutex.Unlock()
+	state.mu.RUnlock()
+
+	// ?format=json devuelve solo los contadores, para scripts y para cmd/admin
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+		return
+	}
 
 	data := struct {
 		ServerStats
-		WaitingPlayersList []*Player
+		WaitingPlayersList []waitingEntry
+		AwayPlayersList    []*Player
 		ActiveRoomsList    map[string][]string
 	}{
 		ServerStats:        stats,
 		WaitingPlayersList: waitingPlayers,
+		AwayPlayersList:    awayPlayers,
 		ActiveRoomsList:    roomsCopy,
 	}
 
-	w.Header().Set("Content-Type", "text/html")
-	statsTemplate.Execute(w, data)
+	renderTemplate(w, r, "stats.html", data)
 }
 
-func handleJoin(w http.ResponseWriter, r *http.Request) {
+func handleJoin(w http.ResponseWriter, r *http.Request, cfg *Config) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	query := r.URL.Query()
 	playerID := query.Get("id")
@@ -185,19 +435,39 @@ func handleJoin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	player := &Player{
-		ID:         playerID,
-		Matched:    false,
-		CreatedAt:  time.Now(),
-		OpponentID: make(chan string, 1),
-		RoomID:     "",
+	platform := query.Get("platform")
+	if platform != "" && !slices.Contains(platforms, platform) {
+		http.Error(w, "Invalid platform", http.StatusBadRequest)
+		return
 	}
 
-	poolMutex.Lock()
-	defer poolMutex.Unlock()
+	elo := defaultELO
+	if v := query.Get("elo"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid elo", http.StatusBadRequest)
+			return
+		}
+		elo = n
+	}
 
-	players[playerID] = player
-	pool = append(pool, player)
+	name, avatarURL, err := parseProfile(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !checkBanned(w, playerID) || !checkDraining(w) || !checkJoinLimits(w, r, cfg.MaxPoolSize) {
+		return
+	}
+
+	player := newPlayer(playerID, platform, name, avatarURL, elo)
+	if !enqueuePlayer(player) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "player already in queue"})
+		return
+	}
+	slog.InfoContext(r.Context(), "player joined", "player_id", playerID, "platform", platform, "elo", elo)
 
 	response := map[string]string{
 		"status":   "waiting",
@@ -205,34 +475,128 @@ func handleJoin(w http.ResponseWriter, r *http.Request) {
 	}
 	json.NewEncoder(w).Encode(response)
 }
-func handleCancel(w http.ResponseWriter, r *http.Request) {
-	playerID := r.URL.Query().Get("id")
+
+// newPlayer crea un jugador en espera, aún sin añadir a la cola.
+func newPlayer(playerID, platform, name, avatarURL string, elo int) *Player {
+	now := time.Now()
+	return &Player{
+		ID:          playerID,
+		Matched:     false,
+		CreatedAt:   now,
+		OpponentIDs: make(chan []string, 1),
+		Cancelled:   make(chan struct{}),
+		RoomID:      "",
+		Platform:    platform,
+		Name:        name,
+		AvatarURL:   avatarURL,
+		ELO:         elo,
+		LastSeen:    now,
+	}
+}
+
+// enqueuePlayer da de alta a player y lo añade al pool. Devuelve false, sin tocar nada,
+// si ya hay un jugador con su ID.
+func enqueuePlayer(player *Player) bool {
+	// El número de secuencia se asigna antes del lock: insertBySeq corrige el orden
+	// si otra petición con un número posterior entra primero.
+	entry := &PoolEntry{Seq: poolSeq.Add(1), Player: player}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	// La comprobación va bajo el mismo lock que el alta: dos /join simultáneos con el
+	// mismo ID dejarían dos entradas en el pool
+	if _, exists := state.players[player.ID]; exists {
+		return false
+	}
+
+	state.players[player.ID] = player
+	insertBySeq(entry)
+	return true
+}
+
+// handlePlayerWait atiende /player-wait/<id> con los segundos que lleva esperando el
+// jugador, para que el cliente pueda mostrar un contador sin guardar CreatedAt.
+func handlePlayerWait(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	playerID := r.URL.Path[len("/player-wait/"):]
 	if playerID == "" {
 		http.Error(w, "ID is required", http.StatusBadRequest)
 		return
 	}
 
-	poolMutex.Lock()
-	defer poolMutex.Unlock()
+	state.mu.RLock()
+	player, exists := state.players[playerID]
+	var createdAt time.Time
+	if exists {
+		createdAt = player.CreatedAt
+	}
+	state.mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Player not found", http.StatusNotFound)
+		return
+	}
 
-	// Eliminar jugador de players map
-	delete(players, playerID)
+	json.NewEncoder(w).Encode(map[string]any{
+		"playerID":    playerID,
+		"waitSeconds": int(time.Since(createdAt).Seconds()),
+	})
+}
 
-	// Eliminar de pool slice
-	for i, p := range pool {
-		if p.ID == playerID {
-			pool = append(pool[:i], pool[i+1:]...)
-			break
-		}
+func handleCancel(w http.ResponseWriter, r *http.Request) {
+	playerID := r.URL.Query().Get("id")
+	if playerID == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
 	}
 
+	cancelPlayer(r.Context(), playerID)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "cancelled"})
 }
 
+// cancelPlayer saca al jugador de la cola a petición suya.
+func cancelPlayer(ctx context.Context, playerID string) {
+	state.mu.Lock()
+	removePlayer(playerID)
+	state.mu.Unlock()
+	slog.InfoContext(ctx, "player cancelled", "player_id", playerID)
+}
+
+// removePlayer elimina al jugador del players map y del pool. Requiere state.mu.
+func removePlayer(playerID string) {
+	if p, ok := state.players[playerID]; ok && !p.Matched {
+		close(p.Cancelled)
+		cancelledPlayers.Inc()
+	}
+	delete(state.players, playerID)
+
+	removeFromPool(func(e *PoolEntry) bool { return e.Player.ID == playerID })
+}
+
+// flushPool retira de la cola a todos los jugadores en espera y deja a cada uno un aviso
+// con reason para /status, SSE y websocket. Devuelve cuántos se han retirado.
+func flushPool(reason string) int {
+	now := time.Now()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	flushed := len(state.pool)
+	for _, e := range state.pool {
+		delete(state.players, e.Player.ID)
+		close(e.Player.Cancelled)
+		state.cancelNotices[e.Player.ID] = cancelNotice{Reason: reason, At: now}
+	}
+	state.pool, state.byELO = nil, nil
+	cancelledPlayers.Add(float64(flushed))
+	return flushed
+}
+
 func handleStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	playerID := r.URL.Path[len("/status/"):]
 	if playerID == "" {
@@ -240,9 +604,27 @@ func handleStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	poolMutex.Lock()
-	player, exists := players[playerID]
-	poolMutex.Unlock()
+	state.mu.Lock()
+	player, exists := state.players[playerID]
+	if exists {
+		// Cada consulta de estado cuenta como latido del jugador
+		player.LastSeen = time.Now()
+	}
+	notice, cancelled := state.cancelNotices[playerID]
+	if cancelled {
+		delete(state.cancelNotices, playerID)
+	}
+	state.mu.Unlock()
+
+	slog.DebugContext(r.Context(), "status poll", "player_id", playerID, "found", exists)
+
+	if !exists && cancelled {
+		json.NewEncoder(w).Encode(map[string]string{
+			"status": noticeStatus(notice.Reason),
+			"reason": notice.Reason,
+		})
+		return
+	}
 
 	if !exists {
 		http.Error(w, "Player not found", http.StatusNotFound)
@@ -250,22 +632,110 @@ func handleStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	select {
-	case opponentID := <-player.OpponentID:
-		response := map[string]string{
-			"status":     "matched",
-			"opponentID": opponentID,
-			"roomID":     player.RoomID,
+	case opponentIDs := <-player.OpponentIDs:
+		writeStatusMatched(w, player, opponentIDs)
+		return
+	default:
+	}
+
+	if !isLongPoll(r) {
+		json.NewEncoder(w).Encode(map[string]string{"status": "waiting"})
+		return
+	}
+
+	// Long-polling: esperamos el emparejamiento hasta longPollTimeout para los clientes
+	// que no pueden usar SSE
+	timer := time.NewTimer(longPollTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-r.Context().Done():
+		writeContextError(w, r.Context().Err())
+	case opponentIDs := <-player.OpponentIDs:
+		writeStatusMatched(w, player, opponentIDs)
+	case <-player.Cancelled:
+		reason := takeCancelReason(playerID)
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"status": noticeStatus(reason),
+			"reason": reason,
+		})
+	case <-timer.C:
+		json.NewEncoder(w).Encode(map[string]string{"status": "waiting"})
+	}
+}
+
+// longPollTimeout es lo que espera /status/{id}?wait=true antes de responder waiting.
+const longPollTimeout = 25 * time.Second
+
+// isLongPoll indica si r es una consulta de estado en modo long-polling.
+func isLongPoll(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/status/") && r.URL.Query().Get("wait") == "true"
+}
+
+// writeStatusMatched responde a /status/{id} con la sala de player y da el
+// emparejamiento por entregado.
+func writeStatusMatched(w http.ResponseWriter, player *Player, opponentIDs []string) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":         "matched",
+		"players":        opponentIDs,
+		"roomID":         player.RoomID,
+		"matchQuality":   player.MatchQuality,
+		"opponentName":   player.OpponentName,
+		"opponentAvatar": player.OpponentAvatar,
+	})
+
+	state.mu.Lock()
+	markDelivered(player, opponentIDs)
+	state.mu.Unlock()
+}
+
+// notifyMatched avisa a p de sus compañeros de sala. Si p tiene un websocket devuelve
+// el envío pendiente para hacerlo fuera de state.mu; si no, usa el canal OpponentIDs.
+// Requiere state.mu.
+func notifyMatched(p *Player, opponentIDs []string) *wsPush {
+	if p.Conn != nil {
+		return newWSPush(p, p.Conn, opponentIDs)
+	}
+	p.OpponentIDs <- opponentIDs
+	return nil
+}
+
+// coPlayers devuelve los IDs de la sala excepto playerID.
+func coPlayers(ids []string, playerID string) []string {
+	others := make([]string, 0, len(ids)-1)
+	for _, id := range ids {
+		if id != playerID {
+			others = append(others, id)
 		}
-		json.NewEncoder(w).Encode(response)
+	}
+	return others
+}
 
-		poolMutex.Lock()
-		delete(players, playerID)
-		poolMutex.Unlock()
+// handlePlayer enruta las peticiones bajo /player/{id}/...
+func handlePlayer(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/player/"), "/")
+	if len(parts) == 3 && parts[0] != "" && parts[1] == "block" {
+		handleBlock(w, r, parts[0], parts[2])
+		return
+	}
+	if len(parts) != 2 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	playerID, action := parts[0], parts[1]
+	switch action {
+	case "blocks":
+		handleBlocks(w, r, playerID)
+	case "email-opt-in":
+		handleEmailOptIn(w, r, playerID)
+	case "away":
+		handleAway(w, r, playerID)
+	case "back":
+		handleBack(w, r, playerID)
 	default:
-		response := map[string]string{
-			"status": "waiting",
-		}
-		json.NewEncoder(w).Encode(response)
+		http.NotFound(w, r)
 	}
 }
 
@@ -278,73 +748,253 @@ func extractMode(id string) string {
 	return id[idx:]
 }
 
-func matchPlayers() {
-	for {
-		poolMutex.Lock()
-		paired := false
-		// Iteramos sobre el pool buscando dos jugadores con el mismo modo
-		for i := 0; i < len(pool)-1; i++ {
-			p1 := pool[i]
-			mode1 := extractMode(p1.ID)
-			if mode1 == "" {
-				continue // Si no se encuentra "modo" en el id, lo saltamos
-			}
-			for j := i + 1; j < len(pool); j++ {
-				p2 := pool[j]
-				mode2 := extractMode(p2.ID)
-				if mode2 == mode1 {
-					// Se encontró un par con el mismo modo
-					roomID := uuid.New().String()
-					p1.RoomID = roomID
-					p2.RoomID = roomID
-					p1.Matched = true
-					p2.Matched = true
-
-					// Removemos ambos jugadores del pool.
-					// Primero removemos el de índice mayor para no afectar el índice del otro.
-					pool = append(pool[:j], pool[j+1:]...)
-					pool = append(pool[:i], pool[i+1:]...)
-
-					// Guardamos la sala en el mapa de rooms
-					roomMutex.Lock()
-					rooms[roomID] = []string{p1.ID, p2.ID}
-					roomMutex.Unlock()
-
-					// Notificamos a los jugadores
-					p1.OpponentID <- p2.ID
-					p2.OpponentID <- p1.ID
-
-					paired = true
-					break
-				}
-			}
-			if paired {
-				break
-			}
+// insertBySeq añade e al pool en la posición que le corresponde por número de
+// secuencia. Casi siempre es el final, pero una petición que obtuvo su número antes
+// puede tomar el lock después. También lo añade a byELO. Requiere state.mu.
+func insertBySeq(e *PoolEntry) {
+	idx := sort.Search(len(state.pool), func(k int) bool { return state.pool[k].Seq > e.Seq })
+	state.pool = slices.Insert(state.pool, idx, e)
+
+	idx, _ = slices.BinarySearchFunc(state.byELO, e, compareELO)
+	state.byELO = slices.Insert(state.byELO, idx, e)
+}
+
+// removeFromPool quita de pool y de byELO las entradas que cumplen del, conservando el
+// orden del resto. Requiere state.mu.
+func removeFromPool(del func(*PoolEntry) bool) {
+	state.pool = slices.DeleteFunc(state.pool, del)
+	state.byELO = slices.DeleteFunc(state.byELO, del)
+}
+
+// compareELO es el orden de byELO: por ELO y, a igual ELO, por orden de llegada.
+func compareELO(a, b *PoolEntry) int {
+	if c := cmp.Compare(a.Player.ELO, b.Player.ELO); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Seq, b.Seq)
+}
+
+// eloWindowFor devuelve la diferencia de ELO aceptable tras esperar wait.
+func eloWindowFor(cfg *Config, wait time.Duration) int {
+	return cfg.ELOWindow + cfg.ELOWindowStep*int(wait/eloWindowInterval)
+}
+
+// oldestWait devuelve lo que lleva esperando el jugador más antiguo del pool. Requiere
+// state.mu.
+func oldestWait(now time.Time) time.Duration {
+	var oldest time.Duration
+	for _, e := range state.pool {
+		oldest = max(oldest, now.Sub(e.Player.CreatedAt))
+	}
+	return oldest
+}
+
+// findGroup busca cfg.RoomSize-1 jugadores del mismo modo que anchor, eligiendo los de
+// ELO más cercano. La ventana de ELO y el cruce de plataformas se deciden por pareja con
+// la espera del que más lleva de los dos, así que un recién llegado puede emparejarse
+// con alguien que ya ha ampliado su ventana. Prefiere la misma plataforma y solo cruza
+// plataformas cuando uno de los dos lleva esperando más de cfg.PlatformTimeout. Nunca
+// junta a dos jugadores si uno ha bloqueado al otro. maxWait es la espera más larga del
+// pool y solo acota la búsqueda. Devuelve el grupo completo ordenado por ELO, o nil si no
+// hay suficientes jugadores. Requiere state.mu.
+func findGroup(cfg *Config, anchor *PoolEntry, mode string, now time.Time, maxWait time.Duration) []*Player {
+	p1 := anchor.Player
+	wait := now.Sub(p1.CreatedAt)
+	byELO := state.byELO
+
+	// Ningún rival puede tener una ventana mayor que la de la espera más larga: saltamos
+	// directamente a ese límite inferior
+	bound := eloWindowFor(cfg, max(wait, maxWait))
+	lo := sort.Search(len(byELO), func(k int) bool { return byELO[k].Player.ELO >= p1.ELO-bound })
+
+	var same, cross []*Player
+	for j := lo; j < len(byELO) && byELO[j].Player.ELO <= p1.ELO+bound; j++ {
+		p2 := byELO[j].Player
+		if p2 == p1 || p2.IsAway || extractMode(p2.ID) != mode {
+			continue
+		}
+		pairWait := max(wait, now.Sub(p2.CreatedAt))
+		if eloDistance(p1, p2) > eloWindowFor(cfg, pairWait) {
+			continue
 		}
-		poolMutex.Unlock()
-		time.Sleep(1 * time.Second)
+		if samePlatform(p1, p2) {
+			same = append(same, p2)
+		} else if pairWait > cfg.PlatformTimeout {
+			cross = append(cross, p2)
+		}
+	}
+
+	byDistance := func(ps []*Player) {
+		slices.SortStableFunc(ps, func(a, b *Player) int { return eloDistance(a, p1) - eloDistance(b, p1) })
 	}
+	byDistance(same)
+	byDistance(cross)
+
+	candidates := append(same, cross...)
+
+	// Completamos el grupo con los candidatos más cercanos que no tengan bloqueos con
+	// ninguno de los ya elegidos
+	group := []*Player{p1}
+	for _, c := range candidates {
+		if len(group) == cfg.RoomSize {
+			break
+		}
+		if !slices.ContainsFunc(group, func(g *Player) bool { return isBlocked(g.ID, c.ID) }) {
+			group = append(group, c)
+		}
+	}
+	if len(group) < cfg.RoomSize {
+		return nil
+	}
+
+	slices.SortFunc(group, func(a, b *Player) int { return a.ELO - b.ELO })
+	return group
+}
+
+func eloDistance(a, b *Player) int {
+	if a.ELO > b.ELO {
+		return a.ELO - b.ELO
+	}
+	return b.ELO - a.ELO
 }
 
-func cleanupOldRooms() {
+// samePlatform indica si dos jugadores pueden emparejarse sin cruzar plataformas.
+// Un jugador sin plataforma declarada es compatible con cualquiera.
+func samePlatform(p1, p2 *Player) bool {
+	return p1.Platform == "" || p2.Platform == "" || p1.Platform == p2.Platform
+}
+
+// matchPlayers empareja jugadores cada segundo hasta que se cancela ctx.
+func matchPlayers(ctx context.Context, cfg *Config) {
+	defer matcherRunning.Store(false)
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
 	for {
-		time.Sleep(5 * time.Minute)
-		poolMutex.Lock()
-		roomMutex.Lock()
+		if ctx.Err() != nil {
+			return
+		}
 
-		for room, roomPlayers := range rooms {
-			_, p1Exists := players[roomPlayers[0]]
-			_, p2Exists := players[roomPlayers[1]]
+		matchRound(cfg)
+		matcherRunning.Store(true)
 
-			// Eliminar sala si algún jugador no existe
-			if !p1Exists || !p2Exists {
-				delete(rooms, room)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// matchRound crea como mucho una sala con los primeros jugadores compatibles del pool.
+// Devuelve el registro de la sala creada, o nil si no ha podido formar ninguna.
+func matchRound(cfg *Config) *MatchRecord {
+	state.mu.Lock()
+	expireAwayPlayers(cfg.AwayTimeout)
+	var pushes []*wsPush
+	var created *MatchRecord
+	var waits []time.Duration
+	// Recorremos el pool en orden de llegada buscando cfg.RoomSize jugadores con el
+	// mismo modo y ELO compatible
+	now := time.Now()
+	maxWait := oldestWait(now)
+	for _, entry := range state.pool {
+		p1 := entry.Player
+		if p1.IsAway {
+			continue
+		}
+		mode1 := extractMode(p1.ID)
+		if mode1 == "" {
+			continue // Si no se encuentra "modo" en el id, lo saltamos
+		}
+		roomPlayers := findGroup(cfg, entry, mode1, now, maxWait)
+		if roomPlayers == nil {
+			continue
+		}
+
+		roomID := uuid.New().String()
+		ids := make([]string, len(roomPlayers))
+		for k, p := range roomPlayers {
+			ids[k] = p.ID
+		}
+
+		// El grupo está ordenado por ELO, así que los extremos dan la diferencia
+		quality := roomPlayers[len(roomPlayers)-1].ELO - roomPlayers[0].ELO
+		for _, p := range roomPlayers {
+			p.RoomID = roomID
+			p.Matched = true
+			p.MatchQuality = quality
+		}
+		setOpponentProfiles(roomPlayers)
+
+		// Removemos el grupo del pool; removeFromPool conserva el orden del resto
+		removeFromPool(func(e *PoolEntry) bool { return slices.Contains(roomPlayers, e.Player) })
+
+		// Guardamos la sala en el mapa de rooms; ya está completa, así que empieza
+		created = &MatchRecord{RoomID: roomID, Players: ids, CreatedAt: time.Now()}
+		room := newRoom(roomID, ids, created.CreatedAt)
+		room.transition(RoomActive, created.CreatedAt)
+		state.rooms[roomID] = room
+		for _, p := range roomPlayers {
+			waits = append(waits, created.CreatedAt.Sub(p.CreatedAt))
+		}
+
+		// Notificamos a los jugadores: por websocket si están conectados, si no por canal
+		for _, p := range roomPlayers {
+			if push := notifyMatched(p, coPlayers(ids, p.ID)); push != nil {
+				pushes = append(pushes, push)
 			}
+			notifier.notifyMatchFound(p.ID, roomID)
 		}
+		break
+	}
+	state.mu.Unlock()
 
-		roomMutex.Unlock()
-		poolMutex.Unlock()
+	// Las escrituras de red y de disco se hacen fuera del lock
+	for _, p := range pushes {
+		p.deliver()
+	}
+	if created != nil {
+		slog.Info("match created", "room_id", created.RoomID, "players", created.Players)
+		matchesCreated.Inc()
+		for _, wait := range waits {
+			matchWait.Observe(wait.Seconds())
+		}
+		recordMatchCreated(created.RoomID, created.Players, created.CreatedAt)
 	}
+	return created
+}
+
+// cleanupOldRooms expira cada interval las salas terminadas y las activas desde hace
+// más de roomTimeout hasta que se cancela ctx.
+func cleanupOldRooms(ctx context.Context, interval, roomTimeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		state.mu.Lock()
 
+		expireReconnecting()
+
+		ended := expireRooms(roomTimeout, time.Now())
+
+		expireCancelNotices()
+		expireBans()
+		expirePrivateRooms()
+		expireTournaments()
+
+		state.mu.Unlock()
+
+		now := time.Now()
+		for _, room := range ended {
+			slog.Info("room closed", "room_id", room)
+			recordMatchEnded(room, now)
+		}
+	}
 }
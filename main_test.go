@@ -0,0 +1,317 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// resetState sustituye el estado global por uno vacío y abre un historial SQLite en un
+// directorio temporal, para que cada test empiece desde cero.
+func resetState(t *testing.T) {
+	t.Helper()
+
+	state = newServerState()
+	leaderboard = &Leaderboard{entries: make(map[string]*LeaderboardEntry)}
+	queueSnapshotsMutex.Lock()
+	queueSnapshots = queueSnapshots[:0]
+	queueSnapshotsMutex.Unlock()
+	joinLimitersMu.Lock()
+	joinLimiters = make(map[string]*ipLimiter)
+	joinLimitersMu.Unlock()
+
+	conn, err := openHistory(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = conn
+	t.Cleanup(func() { conn.Close() })
+}
+
+// join llama a handleJoin con la query dada desde remoteAddr.
+func join(cfg *Config, query, remoteAddr string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/join?"+query, nil)
+	r.RemoteAddr = remoteAddr
+	handleJoin(w, r, cfg)
+	return w
+}
+
+func TestConcurrentJoinsMatchInSeqOrder(t *testing.T) {
+	resetState(t)
+	cfg := defaultConfig()
+
+	// 100 goroutines: las pares solo entran en cola y las impares entran y cancelan,
+	// de modo que las bajas del pool se cruzan con las inserciones
+	const goroutines = 100
+	const players = goroutines / 2
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := fmt.Sprintf("p%d_modo_fifo", i)
+			// Una IP por jugador para no chocar con el límite de /join
+			w := join(&cfg, "id="+id, fmt.Sprintf("198.51.100.%d:1234", i+1))
+			if w.Code != http.StatusOK {
+				t.Errorf("join %s: status %d", id, w.Code)
+			}
+			if i%2 == 1 {
+				w := httptest.NewRecorder()
+				handleCancel(w, httptest.NewRequest(http.MethodPost, "/cancel?id="+id, nil))
+				if w.Code != http.StatusOK {
+					t.Errorf("cancel %s: status %d", id, w.Code)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(state.byELO) != len(state.pool) || len(state.players) != len(state.pool) {
+		t.Fatalf("pool has %d entries, byELO %d, players %d", len(state.pool), len(state.byELO), len(state.players))
+	}
+	for _, e := range state.pool {
+		var n int
+		fmt.Sscanf(e.Player.ID, "p%d_", &n)
+		if n%2 == 1 {
+			t.Fatalf("cancelled player %s still in the pool", e.Player.ID)
+		}
+	}
+	if len(state.pool) != players {
+		t.Fatalf("pool has %d players, want %d", len(state.pool), players)
+	}
+	if !slices.IsSortedFunc(state.pool, func(a, b *PoolEntry) int { return cmp.Compare(a.Seq, b.Seq) }) {
+		t.Fatal("pool is not sorted by Seq")
+	}
+
+	// Todos tienen el mismo ELO y plataforma: cada sala debe juntar a los dos primeros
+	// del pool por orden de llegada
+	var arrival []string
+	for _, e := range state.pool {
+		arrival = append(arrival, e.Player.ID)
+	}
+	for k := 0; k < players; k += 2 {
+		created := matchRound(&cfg)
+		if created == nil {
+			t.Fatalf("round %d created no room", k/2)
+		}
+		got := slices.Clone(created.Players)
+		slices.Sort(got)
+		want := []string{arrival[k], arrival[k+1]}
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Fatalf("room %d = %v, want %v", k/2, created.Players, want)
+		}
+	}
+	if len(state.pool) != 0 {
+		t.Errorf("pool still has %d players", len(state.pool))
+	}
+}
+
+// addWaiting pone en cola a un jugador que lleva wait esperando.
+func addWaiting(t *testing.T, id string, elo int, platform string, wait time.Duration, now time.Time) *PoolEntry {
+	t.Helper()
+	p := newPlayer(id, platform, "", "", elo)
+	p.CreatedAt = now.Add(-wait)
+	if !enqueuePlayer(p) {
+		t.Fatalf("player %s already queued", id)
+	}
+	for _, e := range state.pool {
+		if e.Player == p {
+			return e
+		}
+	}
+	t.Fatalf("player %s not in pool", id)
+	return nil
+}
+
+func TestELOWindowGrowth(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.ELOWindow, cfg.ELOWindowStep = 200, 50
+
+	tests := []struct {
+		wait time.Duration
+		want int
+	}{
+		{0, 200},
+		{9 * time.Second, 200},
+		{10 * time.Second, 250},
+		{25 * time.Second, 300},
+		{time.Minute, 500},
+	}
+	for _, tt := range tests {
+		if got := eloWindowFor(&cfg, tt.wait); got != tt.want {
+			t.Errorf("eloWindowFor(%s) = %d, want %d", tt.wait, got, tt.want)
+		}
+	}
+}
+
+func TestFindGroup(t *testing.T) {
+	type waiting struct {
+		id       string
+		elo      int
+		platform string
+		wait     time.Duration
+	}
+	tests := []struct {
+		name   string
+		anchor waiting
+		others []waiting
+		want   []string // IDs del grupo ordenado por ELO; nil si no hay grupo
+	}{
+		{
+			name:   "only the anchor queued",
+			anchor: waiting{"a_modo", 1200, "", 10 * time.Minute},
+		},
+		{
+			name:   "within base window",
+			anchor: waiting{"a_modo", 1200, "", 0},
+			others: []waiting{{"b_modo", 1350, "", 0}},
+			want:   []string{"a_modo", "b_modo"},
+		},
+		{
+			name:   "outside base window",
+			anchor: waiting{"a_modo", 1200, "", 0},
+			others: []waiting{{"b_modo", 1450, "", 0}},
+		},
+		{
+			name:   "anchor window grown",
+			anchor: waiting{"a_modo", 1200, "", 30 * time.Second},
+			others: []waiting{{"b_modo", 1450, "", 0}},
+			want:   []string{"a_modo", "b_modo"},
+		},
+		{
+			// El recién llegado hereda la ventana del que lleva más esperando
+			name:   "newcomer matches long waiter",
+			anchor: waiting{"a_modo", 1200, "", 0},
+			others: []waiting{{"b_modo", 1450, "", 30 * time.Second}},
+			want:   []string{"a_modo", "b_modo"},
+		},
+		{
+			name:   "closest ELO wins",
+			anchor: waiting{"a_modo", 1200, "", 0},
+			others: []waiting{{"far_modo", 1390, "", 0}, {"near_modo", 1180, "", 0}},
+			want:   []string{"near_modo", "a_modo"},
+		},
+		{
+			name:   "different mode",
+			anchor: waiting{"a_modo1", 1200, "", 0},
+			others: []waiting{{"b_modo2", 1200, "", 0}},
+		},
+		{
+			name:   "same platform preferred",
+			anchor: waiting{"a_modo", 1200, "mobile", 2 * time.Minute},
+			others: []waiting{{"pc_modo", 1200, "desktop", 0}, {"phone_modo", 1300, "mobile", 0}},
+			want:   []string{"a_modo", "phone_modo"},
+		},
+		{
+			name:   "no cross platform before timeout",
+			anchor: waiting{"a_modo", 1200, "mobile", 0},
+			others: []waiting{{"pc_modo", 1200, "desktop", 0}},
+		},
+		{
+			name:   "cross platform when the other waited long",
+			anchor: waiting{"a_modo", 1200, "mobile", 0},
+			others: []waiting{{"pc_modo", 1200, "desktop", 2 * time.Minute}},
+			want:   []string{"a_modo", "pc_modo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetState(t)
+			cfg := defaultConfig()
+			cfg.ELOWindow, cfg.ELOWindowStep, cfg.PlatformTimeout = 200, 50, time.Minute
+			now := time.Now()
+
+			anchor := addWaiting(t, tt.anchor.id, tt.anchor.elo, tt.anchor.platform, tt.anchor.wait, now)
+			for _, o := range tt.others {
+				addWaiting(t, o.id, o.elo, o.platform, o.wait, now)
+			}
+
+			group := findGroup(&cfg, anchor, extractMode(tt.anchor.id), now, oldestWait(now))
+			var got []string
+			for _, p := range group {
+				got = append(got, p.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("group = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchRoundSinglePlayer(t *testing.T) {
+	resetState(t)
+	cfg := defaultConfig()
+	e := addWaiting(t, "a_modo", 1200, "", 10*time.Minute, time.Now())
+
+	if created := matchRound(&cfg); created != nil {
+		t.Fatalf("matchRound created room %s with a single player", created.RoomID)
+	}
+	if len(state.pool) != 1 || state.pool[0] != e || len(state.byELO) != 1 {
+		t.Fatalf("pool changed: %d entries", len(state.pool))
+	}
+	if p := state.players["a_modo"]; p != e.Player || p.Matched {
+		t.Errorf("player changed: %+v", p)
+	}
+}
+
+func TestDuplicateJoinConflict(t *testing.T) {
+	resetState(t)
+	cfg := defaultConfig()
+
+	// Las dos peticiones salen a la vez tras la barrera: solo el lock de enqueuePlayer
+	// puede decidir cuál entra
+	queries := []string{"id=p1_modo&elo=1500", "id=p1_modo&elo=900"}
+	responses := make([]*httptest.ResponseRecorder, len(queries))
+	barrier := make(chan struct{})
+	var wg sync.WaitGroup
+	for k, query := range queries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-barrier
+			responses[k] = join(&cfg, query, fmt.Sprintf("192.0.2.%d:1234", k+1))
+		}()
+	}
+	close(barrier)
+	wg.Wait()
+
+	var codes []int
+	var winner string
+	for k, w := range responses {
+		codes = append(codes, w.Code)
+		switch w.Code {
+		case http.StatusOK:
+			winner = queries[k]
+		case http.StatusConflict:
+			if !strings.Contains(w.Body.String(), "player already in queue") {
+				t.Errorf("409 body = %s", w.Body)
+			}
+		}
+	}
+	slices.Sort(codes)
+	if !slices.Equal(codes, []int{http.StatusOK, http.StatusConflict}) {
+		t.Fatalf("status codes = %v, want [200 409]", codes)
+	}
+
+	if len(state.pool) != 1 || len(state.byELO) != 1 || len(state.players) != 1 {
+		t.Fatalf("pool has %d entries, byELO %d, players %d, want one each", len(state.pool), len(state.byELO), len(state.players))
+	}
+	// El jugador en cola es el de la petición que recibió el 200
+	wantELO := 1500
+	if strings.HasSuffix(winner, "elo=900") {
+		wantELO = 900
+	}
+	if p := state.players["p1_modo"]; p != state.pool[0].Player || p.ELO != wantELO {
+		t.Errorf("queued player %+v, want the one with elo %d", p, wantELO)
+	}
+}
@@ -0,0 +1,267 @@
+// Servicio gRPC de emparejamiento. Expone las mismas operaciones que /join,
+// /events/{id}, /cancel y /report-result sobre el mismo estado del servidor.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: matchmakerpb/matchmaker.proto
+
+package matchmakerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	Matchmaker_JoinQueue_FullMethodName    = "/diceball.matchmaker.Matchmaker/JoinQueue"
+	Matchmaker_WatchStatus_FullMethodName  = "/diceball.matchmaker.Matchmaker/WatchStatus"
+	Matchmaker_Cancel_FullMethodName       = "/diceball.matchmaker.Matchmaker/Cancel"
+	Matchmaker_ReportResult_FullMethodName = "/diceball.matchmaker.Matchmaker/ReportResult"
+)
+
+// MatchmakerClient is the client API for Matchmaker service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MatchmakerClient interface {
+	// JoinQueue pone al jugador en cola, como /join.
+	JoinQueue(ctx context.Context, in *JoinRequest, opts ...grpc.CallOption) (*JoinResponse, error)
+	// WatchStatus emite waiting al conectar, heartbeat cada 15s y termina con matched,
+	// cancelled o room_closed, como /events/{id}.
+	WatchStatus(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (Matchmaker_WatchStatusClient, error)
+	// Cancel saca al jugador de la cola, como /cancel.
+	Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error)
+	// ReportResult informa del ganador de una sala, como /report-result. Requiere
+	// "authorization: Bearer <admin token>" en los metadatos.
+	ReportResult(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*ResultResponse, error)
+}
+
+type matchmakerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMatchmakerClient(cc grpc.ClientConnInterface) MatchmakerClient {
+	return &matchmakerClient{cc}
+}
+
+func (c *matchmakerClient) JoinQueue(ctx context.Context, in *JoinRequest, opts ...grpc.CallOption) (*JoinResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JoinResponse)
+	err := c.cc.Invoke(ctx, Matchmaker_JoinQueue_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchmakerClient) WatchStatus(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (Matchmaker_WatchStatusClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Matchmaker_ServiceDesc.Streams[0], Matchmaker_WatchStatus_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &matchmakerWatchStatusClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Matchmaker_WatchStatusClient interface {
+	Recv() (*StatusEvent, error)
+	grpc.ClientStream
+}
+
+type matchmakerWatchStatusClient struct {
+	grpc.ClientStream
+}
+
+func (x *matchmakerWatchStatusClient) Recv() (*StatusEvent, error) {
+	m := new(StatusEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *matchmakerClient) Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelResponse)
+	err := c.cc.Invoke(ctx, Matchmaker_Cancel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchmakerClient) ReportResult(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*ResultResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResultResponse)
+	err := c.cc.Invoke(ctx, Matchmaker_ReportResult_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MatchmakerServer is the server API for Matchmaker service.
+// All implementations must embed UnimplementedMatchmakerServer
+// for forward compatibility
+type MatchmakerServer interface {
+	// JoinQueue pone al jugador en cola, como /join.
+	JoinQueue(context.Context, *JoinRequest) (*JoinResponse, error)
+	// WatchStatus emite waiting al conectar, heartbeat cada 15s y termina con matched,
+	// cancelled o room_closed, como /events/{id}.
+	WatchStatus(*StatusRequest, Matchmaker_WatchStatusServer) error
+	// Cancel saca al jugador de la cola, como /cancel.
+	Cancel(context.Context, *CancelRequest) (*CancelResponse, error)
+	// ReportResult informa del ganador de una sala, como /report-result. Requiere
+	// "authorization: Bearer <admin token>" en los metadatos.
+	ReportResult(context.Context, *ResultRequest) (*ResultResponse, error)
+	mustEmbedUnimplementedMatchmakerServer()
+}
+
+// UnimplementedMatchmakerServer must be embedded to have forward compatible implementations.
+type UnimplementedMatchmakerServer struct {
+}
+
+func (UnimplementedMatchmakerServer) JoinQueue(context.Context, *JoinRequest) (*JoinResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method JoinQueue not implemented")
+}
+func (UnimplementedMatchmakerServer) WatchStatus(*StatusRequest, Matchmaker_WatchStatusServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchStatus not implemented")
+}
+func (UnimplementedMatchmakerServer) Cancel(context.Context, *CancelRequest) (*CancelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cancel not implemented")
+}
+func (UnimplementedMatchmakerServer) ReportResult(context.Context, *ResultRequest) (*ResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportResult not implemented")
+}
+func (UnimplementedMatchmakerServer) mustEmbedUnimplementedMatchmakerServer() {}
+
+// UnsafeMatchmakerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MatchmakerServer will
+// result in compilation errors.
+type UnsafeMatchmakerServer interface {
+	mustEmbedUnimplementedMatchmakerServer()
+}
+
+func RegisterMatchmakerServer(s grpc.ServiceRegistrar, srv MatchmakerServer) {
+	s.RegisterService(&Matchmaker_ServiceDesc, srv)
+}
+
+func _Matchmaker_JoinQueue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JoinRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchmakerServer).JoinQueue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Matchmaker_JoinQueue_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchmakerServer).JoinQueue(ctx, req.(*JoinRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Matchmaker_WatchStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StatusRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MatchmakerServer).WatchStatus(m, &matchmakerWatchStatusServer{ServerStream: stream})
+}
+
+type Matchmaker_WatchStatusServer interface {
+	Send(*StatusEvent) error
+	grpc.ServerStream
+}
+
+type matchmakerWatchStatusServer struct {
+	grpc.ServerStream
+}
+
+func (x *matchmakerWatchStatusServer) Send(m *StatusEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Matchmaker_Cancel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchmakerServer).Cancel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Matchmaker_Cancel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchmakerServer).Cancel(ctx, req.(*CancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Matchmaker_ReportResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchmakerServer).ReportResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Matchmaker_ReportResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchmakerServer).ReportResult(ctx, req.(*ResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Matchmaker_ServiceDesc is the grpc.ServiceDesc for Matchmaker service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Matchmaker_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "diceball.matchmaker.Matchmaker",
+	HandlerType: (*MatchmakerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "JoinQueue",
+			Handler:    _Matchmaker_JoinQueue_Handler,
+		},
+		{
+			MethodName: "Cancel",
+			Handler:    _Matchmaker_Cancel_Handler,
+		},
+		{
+			MethodName: "ReportResult",
+			Handler:    _Matchmaker_ReportResult_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchStatus",
+			Handler:       _Matchmaker_WatchStatus_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "matchmakerpb/matchmaker.proto",
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// requestDuration mide la latencia por ruta. Se etiqueta con el nombre de la ruta y no
+// con el path para no disparar la cardinalidad con IDs de jugador o sala.
+var requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "http_request_duration_seconds",
+	Help:    "HTTP request latency by route.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"route", "method", "status_code"})
+
+// Métricas de matchmaking. La profundidad del pool y las salas activas se leen en cada
+// scrape; el resto se actualiza fuera de state.mu siempre que se puede.
+var (
+	poolDepth = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "diceball_pool_depth",
+		Help: "Players currently waiting in the matchmaking pool.",
+	}, func() float64 {
+		state.mu.RLock()
+		defer state.mu.RUnlock()
+		return float64(len(state.pool))
+	})
+
+	activeRooms = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "diceball_active_rooms_total",
+		Help: "Rooms currently open.",
+	}, func() float64 {
+		state.mu.RLock()
+		defer state.mu.RUnlock()
+		active := 0
+		for _, room := range state.rooms {
+			if room.State == RoomActive {
+				active++
+			}
+		}
+		return float64(active)
+	})
+
+	matchesCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "diceball_matches_created_total",
+		Help: "Rooms created by the matcher.",
+	})
+
+	matchWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "diceball_match_wait_seconds",
+		Help:    "Time between /join and being placed in a room.",
+		Buckets: []float64{1, 2, 5, 10, 20, 30, 60, 120, 300, 600},
+	})
+
+	cancelledPlayers = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "diceball_cancelled_players_total",
+		Help: "Players that left the pool without being matched.",
+	})
+
+	// goroutineCount es la medida que usa loadShedding, a diferencia de go_goroutines,
+	// que el colector por defecto toma en cada scrape.
+	goroutineCount = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "diceball_goroutines",
+		Help: "Goroutine count measured by the load shedder on the last request.",
+	})
+)
+
+// statusRecorder captura el código de estado escrito por el handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(code int) {
+	rec.status = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+// Flush permite que los handlers de streaming sigan funcionando detrás del middleware.
+func (rec *statusRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack permite actualizar la conexión a websocket detrás del middleware.
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not implement http.Hijacker")
+	}
+	rec.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+// Unwrap expone el ResponseWriter original a http.ResponseController.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// LatencyMiddleware registra la duración de cada petición en requestDuration bajo routeName.
+func LatencyMiddleware(routeName string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			logHTTPError(r, routeName, rec.status)
+			requestDuration.WithLabelValues(routeName, r.Method, strconv.Itoa(rec.status)).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// logHTTPError registra las respuestas de error: Warn para 4xx y Error para 5xx.
+func logHTTPError(r *http.Request, routeName string, status int) {
+	level := slog.LevelWarn
+	switch {
+	case status < 400:
+		return
+	case status >= 500:
+		level = slog.LevelError
+	}
+	slog.Log(r.Context(), level, "http error", "route", routeName, "method", r.Method, "path", r.URL.Path, "status", status)
+}
+
+// handleRoute registra handler en mux bajo pattern, instrumentado con el nombre de ruta name.
+func handleRoute(mux *http.ServeMux, pattern, name string, handler http.HandlerFunc) {
+	mux.Handle(pattern, LatencyMiddleware(name)(handler))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// requestTimeoutDuration es el plazo máximo de las peticiones normales.
+const requestTimeoutDuration = 10 * time.Second
+
+// streamingPrefixes son las rutas de conexión larga que no deben llevar plazo.
+var streamingPrefixes = []string{"/events/", "/ws/", "/spectate/", "/debug/pprof/"}
+
+// StatusClientClosedRequest es el código no estándar 499 para peticiones que el
+// cliente abandonó antes de recibir respuesta.
+const StatusClientClosedRequest = 499
+
+// securityHeaders añade las cabeceras de seguridad estándar a todas las respuestas y
+// elimina la cabecera Server. Las cabeceras CORS las pone cors.
+func securityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Del("Server")
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		h.Set("Permissions-Policy", "camera=(), microphone=(), geolocation=(), payment=(), usb=()")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loadShedding responde 503 cuando el proceso supera maxGoroutines, en lugar de
+// aceptar más trabajo que solo empeoraría la sobrecarga. Cada medida se publica en
+// goroutineCount.
+func loadShedding(maxGoroutines int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := runtime.NumGoroutine()
+		goroutineCount.Set(float64(n))
+		if n > maxGoroutines {
+			http.Error(w, fmt.Sprintf("Server overloaded: %d goroutines", n), http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestTimeout adjunta a cada petición un contexto que vence a los
+// requestTimeoutDuration, salvo en las rutas de streaming. El long-polling de /status/
+// recibe además longPollTimeout. Los handlers que bloquean deben escuchar
+// r.Context().Done().
+func requestTimeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range streamingPrefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		timeout := requestTimeoutDuration
+		if isLongPoll(r) {
+			timeout += longPollTimeout
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// writeContextError responde 504 si venció el plazo de la petición y 499 si el
+// cliente cerró la conexión.
+func writeContextError(w http.ResponseWriter, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		http.Error(w, "Request timed out", http.StatusGatewayTimeout)
+		return
+	}
+	http.Error(w, "Client closed request", StatusClientClosedRequest)
+}
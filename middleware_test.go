@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLoadShedding(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	w := httptest.NewRecorder()
+	loadShedding(1<<20, ok).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("under the limit: status %d, want 200", w.Code)
+	}
+	if n := testutil.ToFloat64(goroutineCount); n < 1 {
+		t.Fatalf("diceball_goroutines = %v after a request, want the measured count", n)
+	}
+
+	// El propio test ya tiene más de una goroutine en marcha
+	w = httptest.NewRecorder()
+	loadShedding(1, ok).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("over the limit: status %d, want 503", w.Code)
+	}
+	// El cuerpo informa de la misma medida que publica el gauge
+	measured := strconv.Itoa(int(testutil.ToFloat64(goroutineCount)))
+	if !strings.Contains(w.Body.String(), measured+" goroutines") {
+		t.Fatalf("body %q does not report the %s goroutines measured", w.Body, measured)
+	}
+}
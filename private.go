@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// inviteCodePattern limita los códigos de invitación a alfanuméricos cortos.
+var inviteCodePattern = regexp.MustCompile(`^[A-Za-z0-9]{4,16}$`)
+
+// privateRoom es una sala creada con /create-room. RoomID queda vacío hasta que entra
+// el invitado; pasado ExpiresAt la invitación ya no se puede usar.
+type privateRoom struct {
+	HostID    string
+	RoomID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// handleCreateRoom atiende /create-room?id=<playerID>&code=<inviteCode>. El anfitrión
+// queda registrado como jugador para /status, /events y /ws, pero no entra en el pool.
+func handleCreateRoom(w http.ResponseWriter, r *http.Request, cfg *Config) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	playerID := query.Get("id")
+	code := query.Get("code")
+
+	if playerID == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+	if !inviteCodePattern.MatchString(code) {
+		http.Error(w, "Invite code must be 4-16 alphanumeric characters", http.StatusBadRequest)
+		return
+	}
+	name, avatarURL, err := parseProfile(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !checkBanned(w, playerID) || !checkDraining(w) {
+		return
+	}
+
+	now := time.Now()
+	player := &Player{
+		ID:          playerID,
+		CreatedAt:   now,
+		OpponentIDs: make(chan []string, 1),
+		Cancelled:   make(chan struct{}),
+		ELO:         defaultELO,
+		LastSeen:    now,
+		Private:     true,
+		Name:        name,
+		AvatarURL:   avatarURL,
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if _, exists := state.privateRooms[code]; exists {
+		http.Error(w, "Invite code already in use", http.StatusConflict)
+		return
+	}
+	if _, exists := state.players[playerID]; exists {
+		http.Error(w, "Player already exists", http.StatusConflict)
+		return
+	}
+
+	state.players[playerID] = player
+	expiresAt := now.Add(cfg.InviteExpiry)
+	state.privateRooms[code] = &privateRoom{HostID: playerID, CreatedAt: now, ExpiresAt: expiresAt}
+	slog.InfoContext(r.Context(), "private room created", "player_id", playerID)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":    "waiting",
+		"playerID":  playerID,
+		"code":      code,
+		"expiresAt": expiresAt,
+	})
+}
+
+// handleJoinPrivate atiende /join-private?id=<playerID>&code=<inviteCode> y empareja al
+// jugador con el anfitrión de la sala en el acto.
+func handleJoinPrivate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	playerID := query.Get("id")
+	code := query.Get("code")
+
+	if playerID == "" || code == "" {
+		http.Error(w, "ID and code are required", http.StatusBadRequest)
+		return
+	}
+	name, avatarURL, err := parseProfile(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !checkBanned(w, playerID) || !checkDraining(w) {
+		return
+	}
+
+	state.mu.Lock()
+
+	pr, exists := state.privateRooms[code]
+	var host *Player
+	if exists {
+		host = state.players[pr.HostID]
+	}
+	// Una invitación cuyo anfitrión ya se fue se trata como desconocida, aunque
+	// cleanupOldRooms aún no la haya borrado. Las caducadas responden 410 hasta entonces.
+	if !exists || (pr.RoomID == "" && host == nil) {
+		state.mu.Unlock()
+		http.Error(w, "Invite code not found", http.StatusNotFound)
+		return
+	}
+	if pr.RoomID == "" && time.Now().After(pr.ExpiresAt) {
+		state.mu.Unlock()
+		http.Error(w, "Invite code expired", http.StatusGone)
+		return
+	}
+	if pr.RoomID != "" {
+		state.mu.Unlock()
+		http.Error(w, "Room is full", http.StatusConflict)
+		return
+	}
+	if _, taken := state.players[playerID]; taken || playerID == pr.HostID {
+		state.mu.Unlock()
+		http.Error(w, "Player already exists", http.StatusConflict)
+		return
+	}
+
+	roomID := uuid.New().String()
+	ids := []string{pr.HostID, playerID}
+	pr.RoomID = roomID
+	host.RoomID = roomID
+	host.Matched = true
+	created := time.Now()
+	room := newRoom(roomID, ids, created)
+	room.transition(RoomActive, created)
+	state.rooms[roomID] = room
+	wait := created.Sub(host.CreatedAt)
+
+	// El invitado recibe la sala en esta misma respuesta, así que pasa directamente a
+	// reconnecting por si la pierde; solo hay que avisar al anfitrión.
+	guest := &Player{
+		ID:        playerID,
+		Matched:   true,
+		CreatedAt: created,
+		RoomID:    roomID,
+		Name:      name,
+		AvatarURL: avatarURL,
+	}
+	setOpponentProfiles([]*Player{host, guest})
+	guest.ReconnectDeadline = created.Add(reconnectWindow)
+	guest.ReconnectData = &ReconnectData{
+		Players:        []string{pr.HostID},
+		RoomID:         roomID,
+		OpponentName:   guest.OpponentName,
+		OpponentAvatar: guest.OpponentAvatar,
+	}
+	state.reconnecting[playerID] = guest
+	push := notifyMatched(host, []string{playerID})
+	notifier.notifyMatchFound(host.ID, roomID)
+	state.mu.Unlock()
+
+	if push != nil {
+		push.deliver()
+	}
+	slog.InfoContext(r.Context(), "match created", "room_id", roomID, "players", ids, "private", true)
+	matchesCreated.Inc()
+	matchWait.Observe(wait.Seconds())
+	recordMatchCreated(roomID, ids, created)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":         "matched",
+		"players":        []string{pr.HostID},
+		"roomID":         roomID,
+		"matchQuality":   0,
+		"opponentName":   guest.OpponentName,
+		"opponentAvatar": guest.OpponentAvatar,
+	})
+}
+
+// handleInvite atiende GET /invite/{code} con el estado de la invitación y su
+// caducidad, para que el cliente muestre una cuenta atrás.
+func handleInvite(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	code := r.URL.Path[len("/invite/"):]
+	if code == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	state.mu.RLock()
+	pr, exists := state.privateRooms[code]
+	var invite privateRoom
+	if exists {
+		invite = *pr
+	}
+	state.mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Invite code not found", http.StatusNotFound)
+		return
+	}
+
+	status := "open"
+	switch {
+	case invite.RoomID != "":
+		status = "full"
+	case time.Now().After(invite.ExpiresAt):
+		status = "expired"
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"code":      code,
+		"hostID":    invite.HostID,
+		"status":    status,
+		"expiresAt": invite.ExpiresAt,
+	})
+}
+
+// expirePrivateRooms retira las invitaciones caducadas sin usar, avisando al
+// anfitrión, y olvida las de salas que ya terminaron. Requiere state.mu.
+func expirePrivateRooms() {
+	now := time.Now()
+	for code, pr := range state.privateRooms {
+		if pr.RoomID != "" {
+			if _, open := state.rooms[pr.RoomID]; !open {
+				delete(state.privateRooms, code)
+			}
+			continue
+		}
+
+		if _, waiting := state.players[pr.HostID]; !waiting {
+			delete(state.privateRooms, code)
+			continue
+		}
+		if now.After(pr.ExpiresAt) {
+			state.cancelNotices[pr.HostID] = cancelNotice{Reason: "invite_expired", At: now}
+			removePlayer(pr.HostID)
+			delete(state.privateRooms, code)
+		}
+	}
+}
+
+// flushPrivateHosts retira a los anfitriones que siguen esperando invitado, con un
+// aviso con reason como el de flushPool. No están en el pool, así que flushPool no los
+// ve. Devuelve cuántos se han retirado.
+func flushPrivateHosts(reason string) int {
+	now := time.Now()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	flushed := 0
+	for code, pr := range state.privateRooms {
+		if pr.RoomID != "" {
+			continue
+		}
+		if _, waiting := state.players[pr.HostID]; waiting {
+			state.cancelNotices[pr.HostID] = cancelNotice{Reason: reason, At: now}
+			removePlayer(pr.HostID)
+			flushed++
+		}
+		delete(state.privateRooms, code)
+	}
+	return flushed
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxNameLength es la longitud máxima, en caracteres, del nombre visible de un jugador.
+const maxNameLength = 32
+
+// parseProfile valida los parámetros opcionales ?name= y ?avatar= de /join y de las
+// salas privadas. Un parámetro ausente deja el campo vacío.
+func parseProfile(query url.Values) (name, avatarURL string, err error) {
+	if query.Has("name") {
+		name = query.Get("name")
+		if err := validateName(name); err != nil {
+			return "", "", err
+		}
+	}
+
+	if query.Has("avatar") {
+		avatarURL = query.Get("avatar")
+		if err := validateAvatar(avatarURL); err != nil {
+			return "", "", err
+		}
+	}
+	return name, avatarURL, nil
+}
+
+func validateName(name string) error {
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return errors.New("Name must be 1-32 characters")
+	}
+	for _, c := range name {
+		if !unicode.IsPrint(c) {
+			return errors.New("Name must contain only printable characters")
+		}
+	}
+	return nil
+}
+
+func validateAvatar(avatarURL string) error {
+	u, err := url.Parse(avatarURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("Avatar must be an http or https URL")
+	}
+	return nil
+}
+
+// setOpponentProfiles copia a cada jugador de una sala de dos el nombre y el avatar
+// de su rival. En salas más grandes no hay un único rival y se dejan vacíos. Requiere
+// state.mu.
+func setOpponentProfiles(group []*Player) {
+	if len(group) != 2 {
+		return
+	}
+	a, b := group[0], group[1]
+	a.OpponentName, a.OpponentAvatar = b.Name, b.AvatarURL
+	b.OpponentName, b.OpponentAvatar = a.Name, a.AvatarURL
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Límite de /join por IP: 5 peticiones por segundo con ráfagas de hasta 10.
+const (
+	joinRate  = rate.Limit(5)
+	joinBurst = 10
+
+	// joinLimiterIdle es el tiempo sin peticiones tras el que se olvida el limitador
+	// de una IP, para que el propio mapa no crezca sin límite.
+	joinLimiterIdle = 3 * time.Minute
+)
+
+type ipLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+var (
+	joinLimiters   = make(map[string]*ipLimiter)
+	joinLimitersMu sync.Mutex
+)
+
+// clientIP devuelve la IP de la conexión. No se confía en X-Forwarded-For porque el
+// servidor no va detrás de un proxy conocido y cualquiera podría falsearla.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// allowJoin consume un token del limitador de la IP. Si no quedan, devuelve false y
+// el tiempo hasta que haya uno disponible.
+func allowJoin(ip string) (bool, time.Duration) {
+	now := time.Now()
+
+	joinLimitersMu.Lock()
+	l, ok := joinLimiters[ip]
+	if !ok {
+		l = &ipLimiter{limiter: rate.NewLimiter(joinRate, joinBurst)}
+		joinLimiters[ip] = l
+	}
+	l.lastSeen = now
+	joinLimitersMu.Unlock()
+
+	res := l.limiter.ReserveN(now, 1)
+	if delay := res.DelayFrom(now); delay > 0 {
+		// No vamos a esperar: devolvemos el token para no penalizar más al cliente
+		res.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// checkJoinLimits aplica el límite por IP y el tamaño máximo del pool, maxPoolSize. Si la petición
+// debe rechazarse escribe la respuesta y devuelve false. Se llama antes de tomar
+// state.mu en escritura para que una avalancha de /join no compita por el lock.
+func checkJoinLimits(w http.ResponseWriter, r *http.Request, maxPoolSize int) bool {
+	if ok, delay := allowJoin(clientIP(r)); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+		http.Error(w, "Too many join requests", http.StatusTooManyRequests)
+		return false
+	}
+
+	if poolFull(maxPoolSize) {
+		http.Error(w, "Matchmaking pool is full", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// poolFull indica si el pool ya tiene maxPoolSize jugadores.
+func poolFull(maxPoolSize int) bool {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	return len(state.pool) >= maxPoolSize
+}
+
+// cleanupJoinLimiters olvida periódicamente los limitadores de IPs inactivas.
+func cleanupJoinLimiters(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		joinLimitersMu.Lock()
+		for ip, l := range joinLimiters {
+			if time.Since(l.lastSeen) > joinLimiterIdle {
+				delete(joinLimiters, ip)
+			}
+		}
+		joinLimitersMu.Unlock()
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestJoinRateLimitPerIP(t *testing.T) {
+	resetState(t)
+	cfg := defaultConfig()
+
+	const requests = 20
+	start := time.Now()
+	var codes []int
+	var retryAfter []string
+	for i := 0; i < requests; i++ {
+		w := join(&cfg, fmt.Sprintf("id=p%d", i), "192.0.2.1:1234")
+		codes = append(codes, w.Code)
+		retryAfter = append(retryAfter, w.Header().Get("Retry-After"))
+	}
+	// A 5 por segundo, si las peticiones tardan más de 200ms se repone un token y el
+	// recuento deja de ser exacto
+	if elapsed := time.Since(start); elapsed >= time.Duration(float64(time.Second)/float64(joinRate)) {
+		t.Skipf("%d joins took %s, too slow to check the burst exactly", requests, elapsed)
+	}
+
+	first429 := slices.Index(codes, http.StatusTooManyRequests)
+	if first429 != joinBurst {
+		t.Fatalf("status codes = %v, want %d 200s before the first 429", codes, joinBurst)
+	}
+	for i, code := range codes {
+		want := http.StatusOK
+		if i >= first429 {
+			want = http.StatusTooManyRequests
+		}
+		if code != want {
+			t.Fatalf("join %d: status %d, want %d (codes %v)", i, code, want, codes)
+		}
+		if code == http.StatusTooManyRequests && retryAfter[i] == "" {
+			t.Errorf("join %d: 429 without Retry-After", i)
+		}
+	}
+	if len(state.pool) != joinBurst {
+		t.Errorf("pool has %d players, want %d", len(state.pool), joinBurst)
+	}
+
+	// Otra IP tiene su propio limitador
+	if w := join(&cfg, "id=other", "192.0.2.2:1234"); w.Code != http.StatusOK {
+		t.Errorf("join from another IP: status %d, want 200", w.Code)
+	}
+}
+
+func TestJoinPoolFull(t *testing.T) {
+	resetState(t)
+	cfg := defaultConfig()
+	cfg.MaxPoolSize = 3
+
+	for i := 0; i < cfg.MaxPoolSize; i++ {
+		if w := join(&cfg, fmt.Sprintf("id=p%d", i), fmt.Sprintf("192.0.2.%d:1234", i+1)); w.Code != http.StatusOK {
+			t.Fatalf("join %d: status %d, want 200", i, w.Code)
+		}
+	}
+
+	if w := join(&cfg, "id=extra", "192.0.2.100:1234"); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("join with a full pool: status %d, want 503", w.Code)
+	}
+	if len(state.pool) != cfg.MaxPoolSize {
+		t.Errorf("pool has %d players, want %d", len(state.pool), cfg.MaxPoolSize)
+	}
+}
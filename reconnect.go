@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// reconnectWindow es el tiempo durante el que /reconnect devuelve la sala de un
+// jugador después de haberle entregado el emparejamiento.
+const reconnectWindow = 60 * time.Second
+
+// ReconnectData es la respuesta de emparejamiento que se guarda para /reconnect.
+type ReconnectData struct {
+	Players        []string
+	RoomID         string
+	MatchQuality   int
+	OpponentName   string
+	OpponentAvatar string
+}
+
+// markDelivered pasa al jugador emparejado de players a reconnecting una vez se le ha
+// entregado la sala, para que pueda recuperarla si la respuesta se perdió. No hace
+// nada si el registro ya no es el suyo. Requiere state.mu.
+func markDelivered(p *Player, opponentIDs []string) {
+	if state.players[p.ID] != p {
+		return
+	}
+	delete(state.players, p.ID)
+
+	p.ReconnectDeadline = time.Now().Add(reconnectWindow)
+	p.ReconnectData = &ReconnectData{
+		Players:        opponentIDs,
+		RoomID:         p.RoomID,
+		MatchQuality:   p.MatchQuality,
+		OpponentName:   p.OpponentName,
+		OpponentAvatar: p.OpponentAvatar,
+	}
+	state.reconnecting[p.ID] = p
+}
+
+// handleReconnect atiende /reconnect?id=<playerID> con la misma respuesta que dio
+// /status al emparejar, mientras no haya pasado reconnectWindow.
+func handleReconnect(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	playerID := r.URL.Query().Get("id")
+	if playerID == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	state.mu.RLock()
+	player, exists := state.reconnecting[playerID]
+	var data ReconnectData
+	if exists {
+		exists = time.Now().Before(player.ReconnectDeadline)
+		data = *player.ReconnectData
+	}
+	state.mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "No recent match for player", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":         "matched",
+		"players":        data.Players,
+		"roomID":         data.RoomID,
+		"matchQuality":   data.MatchQuality,
+		"opponentName":   data.OpponentName,
+		"opponentAvatar": data.OpponentAvatar,
+	})
+}
+
+// expireReconnecting olvida los jugadores cuya ventana de reconexión ha vencido.
+// Requiere state.mu.
+func expireReconnecting() {
+	now := time.Now()
+	for id, p := range state.reconnecting {
+		if now.After(p.ReconnectDeadline) {
+			delete(state.reconnecting, id)
+		}
+	}
+}
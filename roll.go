@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	maxDice  = 6
+	diceFace = 6
+)
+
+// RollRecord es una tirada de dados. WaitingFor es el jugador al que le toca tirar a
+// continuación: los turnos siguen el orden de Room.Players.
+type RollRecord struct {
+	PlayerID   string    `json:"playerID"`
+	Dice       []int     `json:"dice"`
+	Sum        int       `json:"sum"`
+	RolledAt   time.Time `json:"rolledAt"`
+	WaitingFor string    `json:"waitingFor"`
+}
+
+// rollDice tira n dados de seis caras con crypto/rand, para que ningún cliente pueda
+// predecir el resultado.
+func rollDice(n int) ([]int, error) {
+	dice := make([]int, n)
+	for k := range dice {
+		v, err := rand.Int(rand.Reader, big.NewInt(diceFace))
+		if err != nil {
+			return nil, err
+		}
+		dice[k] = int(v.Int64()) + 1
+	}
+	return dice, nil
+}
+
+// nextTurn devuelve el jugador que sigue a playerID en players.
+func nextTurn(players []string, playerID string) string {
+	k := slices.Index(players, playerID)
+	return players[(k+1)%len(players)]
+}
+
+// handleRoll atiende POST /roll?room=<roomID>&player=<playerID>&dice=<N>. Un jugador no
+// puede volver a tirar hasta que le llegue otra vez el turno.
+func handleRoll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	roomID := query.Get("room")
+	playerID := query.Get("player")
+	if roomID == "" || playerID == "" {
+		http.Error(w, "Room and player are required", http.StatusBadRequest)
+		return
+	}
+	n, err := strconv.Atoi(query.Get("dice"))
+	if err != nil || n < 1 || n > maxDice {
+		http.Error(w, "Dice must be between 1 and 6", http.StatusBadRequest)
+		return
+	}
+
+	// Tiramos antes del lock: crypto/rand puede bloquear
+	dice, err := rollDice(n)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "rolling dice failed", "error", err)
+		http.Error(w, "Could not roll dice", http.StatusInternalServerError)
+		return
+	}
+	sum := 0
+	for _, d := range dice {
+		sum += d
+	}
+
+	state.mu.Lock()
+	room, exists := state.rooms[roomID]
+	if !exists {
+		state.mu.Unlock()
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+	if !slices.Contains(room.Players, playerID) {
+		state.mu.Unlock()
+		http.Error(w, "Player is not in this room", http.StatusForbidden)
+		return
+	}
+	if room.State != RoomActive {
+		state.mu.Unlock()
+		http.Error(w, "Room is not active", http.StatusConflict)
+		return
+	}
+	if len(room.Rolls) > 0 && room.Rolls[len(room.Rolls)-1].WaitingFor != playerID {
+		state.mu.Unlock()
+		http.Error(w, "Not your turn", http.StatusConflict)
+		return
+	}
+	roll := RollRecord{
+		PlayerID:   playerID,
+		Dice:       dice,
+		Sum:        sum,
+		RolledAt:   time.Now(),
+		WaitingFor: nextTurn(room.Players, playerID),
+	}
+	room.Rolls = append(room.Rolls, roll)
+	room.broadcast(spectatorEvent{Event: "roll", Data: roll})
+	state.mu.Unlock()
+
+	slog.DebugContext(r.Context(), "dice rolled", "room_id", roomID, "player_id", playerID, "dice", dice)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"rolls": dice,
+		"sum":   sum,
+	})
+}
+
+// handleRolls atiende GET /room/{id}/rolls con todas las tiradas de la sala, en orden.
+func handleRolls(w http.ResponseWriter, r *http.Request, roomID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state.mu.RLock()
+	room, exists := state.rooms[roomID]
+	var rolls []RollRecord
+	if exists {
+		rolls = slices.Clone(room.Rolls)
+	}
+	state.mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+	if rolls == nil {
+		rolls = make([]RollRecord, 0)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"roomID": roomID,
+		"rolls":  rolls,
+	})
+}
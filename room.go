@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RoomState es la fase del ciclo de vida de una sala.
+type RoomState string
+
+// Transiciones permitidas: WAITING → ACTIVE al completarse la sala, ACTIVE → FINISHED
+// con /room/{id}/finish, y FINISHED o ACTIVE → EXPIRED en cleanupOldRooms, que la borra
+// a continuación.
+const (
+	RoomWaiting  RoomState = "WAITING"
+	RoomActive   RoomState = "ACTIVE"
+	RoomFinished RoomState = "FINISHED"
+	RoomExpired  RoomState = "EXPIRED"
+)
+
+var roomTransitions = map[RoomState][]RoomState{
+	RoomWaiting:  {RoomActive, RoomExpired},
+	RoomActive:   {RoomFinished, RoomExpired},
+	RoomFinished: {RoomExpired},
+}
+
+// Room es una sala de juego. Players no se modifica en sitio: se sustituye. Rolls
+// solo crece, así que una copia de la sala sigue viendo un historial coherente.
+type Room struct {
+	ID        string
+	Players   []string
+	State     RoomState
+	CreatedAt time.Time
+	StartedAt time.Time
+	EndedAt   time.Time
+	Rolls     []RollRecord
+
+	// spectators son los canales de los espectadores conectados a /spectate/{id}.
+	spectators []chan spectatorEvent
+	chat       chatRing
+}
+
+// newRoom crea una sala en WAITING.
+func newRoom(id string, players []string, now time.Time) *Room {
+	return &Room{ID: id, Players: players, State: RoomWaiting, CreatedAt: now}
+}
+
+// transition lleva la sala a to si la transición está permitida y fija StartedAt o
+// EndedAt según corresponda. Al terminar la sala se despide a los espectadores.
+// Requiere state.mu.
+func (room *Room) transition(to RoomState, now time.Time) error {
+	if !slices.Contains(roomTransitions[room.State], to) {
+		return fmt.Errorf("cannot move room from %s to %s", room.State, to)
+	}
+	room.State = to
+	switch to {
+	case RoomActive:
+		room.StartedAt = now
+	case RoomFinished, RoomExpired:
+		if room.EndedAt.IsZero() {
+			room.EndedAt = now
+		}
+		room.endSpectators()
+	}
+	return nil
+}
+
+// MarshalJSON omite StartedAt y EndedAt mientras no se han alcanzado.
+func (room *Room) MarshalJSON() ([]byte, error) {
+	optional := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		return &t
+	}
+	return json.Marshal(struct {
+		ID        string     `json:"id"`
+		Players   []string   `json:"players"`
+		State     RoomState  `json:"state"`
+		CreatedAt time.Time  `json:"createdAt"`
+		StartedAt *time.Time `json:"startedAt,omitempty"`
+		EndedAt   *time.Time `json:"endedAt,omitempty"`
+	}{room.ID, room.Players, room.State, room.CreatedAt, optional(room.StartedAt), optional(room.EndedAt)})
+}
+
+// handleRoom atiende GET /room/{id}, POST /room/{id}/finish?id=<playerID>,
+// GET /room/{id}/rolls y /room/{id}/chat.
+func handleRoom(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/room/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		handleGetRoom(w, r, parts[0])
+	case len(parts) == 2 && parts[0] != "" && parts[1] == "finish":
+		handleFinishRoom(w, r, parts[0])
+	case len(parts) == 2 && parts[0] != "" && parts[1] == "rolls":
+		handleRolls(w, r, parts[0])
+	case len(parts) == 2 && parts[0] != "" && parts[1] == "chat":
+		handleChat(w, r, parts[0])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func handleGetRoom(w http.ResponseWriter, r *http.Request, roomID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state.mu.RLock()
+	room, exists := state.rooms[roomID]
+	var snapshot Room
+	if exists {
+		snapshot = *room
+	}
+	state.mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&snapshot)
+}
+
+// handleFinishRoom marca la partida como terminada. Solo puede hacerlo un jugador de
+// la sala, identificado con ?id=.
+func handleFinishRoom(w http.ResponseWriter, r *http.Request, roomID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	playerID := r.URL.Query().Get("id")
+	if playerID == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	state.mu.Lock()
+	room, exists := state.rooms[roomID]
+	if !exists {
+		state.mu.Unlock()
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+	if !slices.Contains(room.Players, playerID) {
+		state.mu.Unlock()
+		http.Error(w, "Player is not in this room", http.StatusForbidden)
+		return
+	}
+	if err := room.transition(RoomFinished, now); err != nil {
+		state.mu.Unlock()
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	snapshot := *room
+	state.mu.Unlock()
+
+	slog.InfoContext(r.Context(), "room finished", "room_id", roomID, "player_id", playerID)
+	recordMatchEnded(roomID, now)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&snapshot)
+}
+
+// expireRooms pasa a EXPIRED y borra las salas terminadas y las que llevan activas más
+// de timeout. Devuelve las salas borradas que aún no tenían fin registrado en el
+// historial. Requiere state.mu.
+func expireRooms(timeout time.Duration, now time.Time) (ended []string) {
+	for id, room := range state.rooms {
+		stale := room.State != RoomFinished && now.Sub(room.CreatedAt) > timeout
+		if room.State != RoomFinished && !stale {
+			continue
+		}
+		if room.State != RoomFinished {
+			ended = append(ended, id)
+		}
+		room.transition(RoomExpired, now)
+		delete(state.rooms, id)
+	}
+	return ended
+}
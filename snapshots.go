@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// QueueEntry es el estado de un jugador en cola en el momento de una captura.
+type QueueEntry struct {
+	PlayerID    string  `json:"playerID"`
+	WaitSeconds float64 `json:"waitSeconds"`
+	Rating      int     `json:"rating"`
+	Platform    string  `json:"platform,omitempty"`
+	IsAway      bool    `json:"isAway"`
+}
+
+// QueueSnapshot es una captura del pool de emparejamiento. Entries no se modifica en
+// sitio: se sustituye, porque handleQueueSnapshots lo codifica fuera del lock.
+type QueueSnapshot struct {
+	Timestamp time.Time    `json:"timestamp"`
+	PoolSize  int          `json:"poolSize"`
+	Entries   []QueueEntry `json:"entries"`
+}
+
+// maxQueueSnapshots es la capacidad del buffer circular (1 hora a 10s por captura).
+const maxQueueSnapshots = 360
+
+var (
+	queueSnapshots      = make([]QueueSnapshot, 0, maxQueueSnapshots)
+	queueSnapshotsMutex sync.Mutex
+)
+
+// captureQueueSnapshots guarda el estado del pool cada interval hasta que se cancela ctx.
+func captureQueueSnapshots(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		state.mu.RLock()
+		snapshot := QueueSnapshot{
+			Timestamp: now,
+			PoolSize:  len(state.pool),
+			Entries:   make([]QueueEntry, 0, len(state.pool)),
+		}
+		for _, e := range state.pool {
+			p := e.Player
+			snapshot.Entries = append(snapshot.Entries, QueueEntry{
+				PlayerID:    p.ID,
+				WaitSeconds: now.Sub(p.CreatedAt).Seconds(),
+				Rating:      p.ELO,
+				Platform:    p.Platform,
+				IsAway:      p.IsAway,
+			})
+		}
+		state.mu.RUnlock()
+
+		queueSnapshotsMutex.Lock()
+		if len(queueSnapshots) == maxQueueSnapshots {
+			// Descartamos la captura más antigua
+			queueSnapshots = append(queueSnapshots[:0], queueSnapshots[1:]...)
+		}
+		queueSnapshots = append(queueSnapshots, snapshot)
+		queueSnapshotsMutex.Unlock()
+	}
+}
+
+// handleQueueSnapshots atiende GET /admin/queue-snapshots?from=ISO8601&to=ISO8601.
+// Ambos límites son opcionales e inclusivos.
+func handleQueueSnapshots(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var from, to time.Time
+	var err error
+	if v := query.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid from timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid to timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+
+	queueSnapshotsMutex.Lock()
+	result := make([]QueueSnapshot, 0, len(queueSnapshots))
+	for _, s := range queueSnapshots {
+		if !from.IsZero() && s.Timestamp.Before(from) {
+			continue
+		}
+		if !to.IsZero() && s.Timestamp.After(to) {
+			continue
+		}
+		result = append(result, s)
+	}
+	queueSnapshotsMutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// spectatorBuffer es cuántos eventos puede acumular un espectador antes de que se le
+// considere lento y se le desconecte.
+const spectatorBuffer = 16
+
+// spectatorEvent es un evento SSE pendiente de enviar a un espectador.
+type spectatorEvent struct {
+	Event string
+	Data  any
+}
+
+// broadcast envía ev a todos los espectadores de la sala sin bloquear: el que tiene el
+// buffer lleno se desconecta. Requiere state.mu.
+func (room *Room) broadcast(ev spectatorEvent) {
+	room.spectators = slices.DeleteFunc(room.spectators, func(ch chan spectatorEvent) bool {
+		select {
+		case ch <- ev:
+			return false
+		default:
+			slog.Warn("dropping slow spectator", "room_id", room.ID)
+			close(ch)
+			return true
+		}
+	})
+}
+
+// endSpectators envía room_ended y cierra todos los espectadores. Requiere state.mu.
+func (room *Room) endSpectators() {
+	room.broadcast(spectatorEvent{Event: "room_ended", Data: map[string]any{
+		"roomID": room.ID,
+		"state":  room.State,
+	}})
+	for _, ch := range room.spectators {
+		close(ch)
+	}
+	room.spectators = nil
+}
+
+// closeSpectators envía server_shutdown a los espectadores de todas las salas y los
+// cierra, para que sus streams terminen antes de server.Shutdown. Devuelve cuántos había.
+func closeSpectators() int {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	closed := 0
+	for _, room := range state.rooms {
+		room.broadcast(spectatorEvent{Event: "server_shutdown", Data: map[string]string{"roomID": room.ID}})
+		for _, ch := range room.spectators {
+			close(ch)
+		}
+		closed += len(room.spectators)
+		room.spectators = nil
+	}
+	return closed
+}
+
+// handleSpectate atiende GET /spectate/{roomID} con Server-Sent Events: envía
+// spectating al conectar, roll con cada tirada, heartbeat cada 15s y room_ended cuando
+// la sala termina, o server_shutdown si se apaga el servidor.
+func handleSpectate(w http.ResponseWriter, r *http.Request) {
+	roomID := r.URL.Path[len("/spectate/"):]
+	if roomID == "" {
+		http.Error(w, "Room ID is required", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan spectatorEvent, spectatorBuffer)
+	state.mu.Lock()
+	room, exists := state.rooms[roomID]
+	var players []string
+	if exists && room.State == RoomActive {
+		room.spectators = append(room.spectators, ch)
+		players = room.Players
+	}
+	state.mu.Unlock()
+
+	if !exists {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+	if players == nil {
+		http.Error(w, "Room is not active", http.StatusConflict)
+		return
+	}
+	// Al desconectarse el espectador lo retiramos, salvo que la sala ya lo haya hecho
+	defer func() {
+		state.mu.Lock()
+		room.spectators = slices.DeleteFunc(room.spectators, func(c chan spectatorEvent) bool { return c == ch })
+		state.mu.Unlock()
+	}()
+
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	writeEvent(w, "spectating", map[string]any{"roomID": roomID, "players": players})
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				// La sala terminó o nos descartó por lentos
+				return
+			}
+			writeEvent(w, ev.Event, ev.Data)
+			flusher.Flush()
+		case <-heartbeat.C:
+			writeEvent(w, "heartbeat", map[string]int64{"time": time.Now().Unix()})
+			flusher.Flush()
+		}
+	}
+}
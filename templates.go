@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+)
+
+//go:embed static templates
+var assets embed.FS
+
+// pageTemplates contiene la página del dashboard y el parcial de /stats que htmx
+// recarga. Se parsean una sola vez al arrancar: un error de sintaxis impide iniciar
+// el servidor en lugar de romper cada petición.
+var pageTemplates = template.Must(template.ParseFS(assets, "static/index.html", "templates/stats.html"))
+
+// renderTemplate ejecuta la plantilla name en un buffer para que un fallo a mitad de
+// render devuelva un 500 en lugar de HTML cortado.
+func renderTemplate(w http.ResponseWriter, r *http.Request, name string, data any) {
+	var buf bytes.Buffer
+	if err := pageTemplates.ExecuteTemplate(&buf, name, data); err != nil {
+		slog.ErrorContext(r.Context(), "rendering template failed", "template", name, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmbeddedTemplatesParse(t *testing.T) {
+	tmpl, err := template.ParseFS(assets, "static/index.html", "templates/stats.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"index.html", "stats.html"} {
+		if tmpl.Lookup(name) == nil {
+			t.Errorf("template %s not found", name)
+		}
+	}
+}
+
+func TestDashboardRenders(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.DashboardRefreshMS = 2500
+
+	w := httptest.NewRecorder()
+	dashboardHandler(w, httptest.NewRequest(http.MethodGet, "/", nil), &cfg)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), `hx-trigger="every 2500ms"`) {
+		t.Error("refresh interval not rendered")
+	}
+}
+
+func TestStatsRenders(t *testing.T) {
+	resetState(t)
+	cfg := defaultConfig()
+
+	join(&cfg, "id=waiting_modo&platform=mobile", "192.0.2.1:1234")
+	p1 := newPlayer("away_modo", "", "", "", defaultELO)
+	p1.IsAway, p1.AwaySince = true, time.Now()
+	enqueuePlayer(p1)
+	state.rooms["room1"] = newRoom("room1", []string{"a", "b"}, time.Now())
+	state.rooms["room1"].transition(RoomActive, time.Now())
+
+	w := httptest.NewRecorder()
+	statsHandler(w, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	for _, want := range []string{"waiting_modo", "away_modo", "room1", "mobile: 1"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("stats page does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package testfixtures carga escenarios de prueba escritos en YAML y los ejecuta contra
+// un http.Handler. Cada escenario es una secuencia de peticiones HTTP con el estado y
+// el fragmento de cuerpo esperados; los pasos que no son HTTP, como una pasada del
+// emparejador, los resuelve quien llama a Run.
+package testfixtures
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture es un escenario: una secuencia de pasos que se ejecutan en orden.
+type Fixture struct {
+	Name string `yaml:"name"`
+	// Config es la ruta de un fichero de configuración para el servidor bajo prueba;
+	// testfixtures no lo interpreta.
+	Config string `yaml:"config"`
+	Steps  []Step `yaml:"steps"`
+}
+
+// Step es una petición del escenario, o una pasada del emparejador si Match es true.
+// Con Repeat > 1 la petición se repite sustituyendo {i} por 1..Repeat en Path,
+// RemoteAddr, Body y ExpectedBody; con Concurrent las repeticiones van en paralelo.
+type Step struct {
+	Method     string            `yaml:"method"`
+	Path       string            `yaml:"path"`
+	Headers    map[string]string `yaml:"headers"`
+	Body       string            `yaml:"body"`
+	RemoteAddr string            `yaml:"remoteAddr"`
+
+	// ExpectedStatus es 200 si no se indica; ExpectedBody debe aparecer en la respuesta.
+	ExpectedStatus int    `yaml:"expectedStatus"`
+	ExpectedBody   string `yaml:"expectedBody"`
+
+	Repeat     int  `yaml:"repeat"`
+	Concurrent bool `yaml:"concurrent"`
+	Match      bool `yaml:"match"`
+}
+
+// Load lee un escenario. Como la configuración del servidor, se decodifica en modo
+// estricto: un campo desconocido es un error y no se ignora.
+func Load(path string) (Fixture, error) {
+	var f Fixture
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return f, fmt.Errorf("reading fixture: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return f, fmt.Errorf("parsing fixture %s: %w", path, err)
+	}
+	if f.Name == "" || len(f.Steps) == 0 {
+		return f, fmt.Errorf("fixture %s needs a name and at least one step", path)
+	}
+	for k, step := range f.Steps {
+		if step.Match == (step.Path != "") {
+			return f, fmt.Errorf("fixture %s: step %d needs either a path or match: true", path, k+1)
+		}
+	}
+	return f, nil
+}
+
+// LoadDir lee todos los escenarios *.yaml de dir, en orden alfabético.
+func LoadDir(dir string) ([]Fixture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no fixtures in %s", dir)
+	}
+
+	var fixtures []Fixture
+	var errs []error
+	for _, path := range paths {
+		f, err := Load(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fixtures = append(fixtures, f)
+	}
+	return fixtures, errors.Join(errs...)
+}
+
+// Run ejecuta los pasos de f contra h y marca t como fallido en cada respuesta que no
+// coincide con lo esperado. Los pasos Match llaman a match.
+func Run(t testing.TB, h http.Handler, f Fixture, match func()) {
+	t.Helper()
+
+	for k, step := range f.Steps {
+		if step.Match {
+			match()
+			continue
+		}
+		runStep(t, h, fmt.Sprintf("step %d", k+1), step)
+	}
+}
+
+// runStep ejecuta las repeticiones de step y comprueba cada respuesta.
+func runStep(t testing.TB, h http.Handler, label string, step Step) {
+	t.Helper()
+
+	repeat := max(step.Repeat, 1)
+	var wg sync.WaitGroup
+	for i := 1; i <= repeat; i++ {
+		do := func() {
+			expand := func(s string) string { return strings.ReplaceAll(s, "{i}", strconv.Itoa(i)) }
+
+			r := httptest.NewRequest(cmp.Or(step.Method, http.MethodGet), expand(step.Path), strings.NewReader(expand(step.Body)))
+			for name, value := range step.Headers {
+				r.Header.Set(name, value)
+			}
+			if step.RemoteAddr != "" {
+				r.RemoteAddr = expand(step.RemoteAddr)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if want := cmp.Or(step.ExpectedStatus, http.StatusOK); w.Code != want {
+				t.Errorf("%s %s %s: status %d, want %d: %s", label, r.Method, r.URL, w.Code, want, w.Body)
+			}
+			if want := expand(step.ExpectedBody); !strings.Contains(w.Body.String(), want) {
+				t.Errorf("%s %s %s: body %q does not contain %q", label, r.Method, r.URL, w.Body, want)
+			}
+		}
+		if !step.Concurrent {
+			do()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			do()
+		}()
+	}
+	wg.Wait()
+}
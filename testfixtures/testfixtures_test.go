@@ -0,0 +1,78 @@
+package testfixtures
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// writeFixture escribe data en un fichero temporal y devuelve su ruta.
+func writeFixture(t *testing.T, data string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "fixture.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRejectsInvalidFixtures(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":  "name: x\nsteps:\n  - path: /a\n    expectedStatuss: 200\n",
+		"no name":        "steps:\n  - path: /a\n",
+		"no steps":       "name: x\n",
+		"path and match": "name: x\nsteps:\n  - path: /a\n    match: true\n",
+		"empty step":     "name: x\nsteps:\n  - method: GET\n",
+	}
+	for name, data := range tests {
+		if _, err := Load(writeFixture(t, data)); err == nil {
+			t.Errorf("%s: loaded without error", name)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	f, err := Load(writeFixture(t, `
+name: echo
+steps:
+  - method: POST
+    path: /echo/{i}
+    remoteAddr: 192.0.2.{i}:1234
+    body: 'hello {i}'
+    headers: {X-Test: yes}
+    repeat: 3
+    concurrent: true
+    expectedBody: 'hello {i} from 192.0.2.{i}:1234'
+  - match: true
+  - path: /missing
+    expectedStatus: 404
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var paths []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("X-Test") != "yes" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(string(body) + " from " + r.RemoteAddr))
+	})
+
+	matches := 0
+	Run(t, mux, f, func() { matches++ })
+	if len(paths) != 3 || matches != 1 {
+		t.Fatalf("ran %d requests and %d match steps, want 3 and 1", len(paths), matches)
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	minTournamentSize = 4
+	maxTournamentSize = 64
+)
+
+// tournamentTTL es el tiempo sin cambios tras el cual cleanupOldRooms olvida un torneo,
+// haya terminado o no.
+const tournamentTTL = time.Hour
+
+// TournamentState es la fase de un torneo: OPEN mientras se inscriben jugadores,
+// RUNNING desde que se llena hasta que se conoce el campeón y FINISHED después.
+type TournamentState string
+
+const (
+	TournamentOpen     TournamentState = "OPEN"
+	TournamentRunning  TournamentState = "RUNNING"
+	TournamentFinished TournamentState = "FINISHED"
+)
+
+// BracketMatch es una partida del cuadro. Winner queda vacío hasta /advance.
+type BracketMatch struct {
+	RoomID  string    `json:"roomID"`
+	Players [2]string `json:"players"`
+	Winner  string    `json:"winner,omitempty"`
+}
+
+// Tournament es un torneo de eliminación directa. Slots son los inscritos por orden
+// de llegada y Rounds las rondas ya generadas; la última es la que se está jugando.
+type Tournament struct {
+	ID        string            `json:"id"`
+	Size      int               `json:"size"`
+	Slots     []string          `json:"slots"`
+	Rounds    [][]*BracketMatch `json:"rounds"`
+	State     TournamentState   `json:"state"`
+	Winner    string            `json:"winner,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// validTournamentSize indica si size es una potencia de dos entre minTournamentSize y
+// maxTournamentSize.
+func validTournamentSize(size int) bool {
+	return size >= minTournamentSize && size <= maxTournamentSize && size&(size-1) == 0
+}
+
+// startRound empareja players de dos en dos, en orden, y crea una sala activa por
+// partida. Devuelve las partidas para registrarlas en el historial fuera del lock.
+// Requiere state.mu.
+func (t *Tournament) startRound(players []string, now time.Time) []*MatchRecord {
+	round := make([]*BracketMatch, 0, len(players)/2)
+	created := make([]*MatchRecord, 0, len(players)/2)
+	for i := 0; i < len(players); i += 2 {
+		roomID := uuid.New().String()
+		ids := []string{players[i], players[i+1]}
+		room := newRoom(roomID, ids, now)
+		room.transition(RoomActive, now)
+		state.rooms[roomID] = room
+
+		round = append(round, &BracketMatch{RoomID: roomID, Players: [2]string{players[i], players[i+1]}})
+		created = append(created, &MatchRecord{RoomID: roomID, Players: ids, CreatedAt: now})
+	}
+	t.Rounds = append(t.Rounds, round)
+	return created
+}
+
+// handleTournament enruta /tournament/create y /tournament/{id}/{join,advance,bracket}.
+func handleTournament(w http.ResponseWriter, r *http.Request, cfg *Config) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/tournament/"), "/")
+	if len(parts) == 1 && parts[0] == "create" {
+		handleCreateTournament(w, r)
+		return
+	}
+	if len(parts) != 2 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	tournamentID, action := parts[0], parts[1]
+	switch action {
+	case "join":
+		handleJoinTournament(w, r, tournamentID)
+	case "advance":
+		// Como /report-result, los resultados solo los informa quien tiene el token
+		requireAdminToken(cfg.AdminToken, func(w http.ResponseWriter, r *http.Request) {
+			handleAdvanceTournament(w, r, tournamentID)
+		})(w, r)
+	case "bracket":
+		handleBracket(w, r, tournamentID)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleCreateTournament atiende POST /tournament/create?size=N.
+func handleCreateTournament(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	size, err := strconv.Atoi(r.URL.Query().Get("size"))
+	if err != nil || !validTournamentSize(size) {
+		http.Error(w, "Size must be a power of 2 between 4 and 64", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	t := &Tournament{
+		ID:        uuid.New().String(),
+		Size:      size,
+		Slots:     make([]string, 0, size),
+		Rounds:    make([][]*BracketMatch, 0),
+		State:     TournamentOpen,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	state.mu.Lock()
+	state.tournaments[t.ID] = t
+	state.mu.Unlock()
+	slog.InfoContext(r.Context(), "tournament created", "tournament_id", t.ID, "size", size)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"tournamentID": t.ID,
+		"size":         size,
+	})
+}
+
+// handleJoinTournament atiende POST /tournament/{id}/join?playerID=<id>. El jugador que
+// completa el cuadro arranca la primera ronda.
+func handleJoinTournament(w http.ResponseWriter, r *http.Request, tournamentID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	playerID := r.URL.Query().Get("playerID")
+	if playerID == "" {
+		http.Error(w, "playerID is required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	state.mu.Lock()
+	t, exists := state.tournaments[tournamentID]
+	if !exists {
+		state.mu.Unlock()
+		http.Error(w, "Tournament not found", http.StatusNotFound)
+		return
+	}
+	if t.State != TournamentOpen {
+		state.mu.Unlock()
+		http.Error(w, "Tournament is full", http.StatusConflict)
+		return
+	}
+	if slices.Contains(t.Slots, playerID) {
+		state.mu.Unlock()
+		http.Error(w, "Player already joined", http.StatusConflict)
+		return
+	}
+
+	t.Slots = append(t.Slots, playerID)
+	t.UpdatedAt = now
+	var created []*MatchRecord
+	if len(t.Slots) == t.Size {
+		t.State = TournamentRunning
+		created = t.startRound(t.Slots, now)
+	}
+	joined, status := len(t.Slots), t.State
+	state.mu.Unlock()
+
+	slog.InfoContext(r.Context(), "tournament joined", "tournament_id", tournamentID, "player_id", playerID)
+	recordTournamentRound(r.Context(), tournamentID, created)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"tournamentID": tournamentID,
+		"joined":       joined,
+		"state":        status,
+	})
+}
+
+// handleAdvanceTournament atiende POST /tournament/{id}/advance?roomID=<rid>&winner=<pid>.
+// Cuando la ronda actual tiene todos sus ganadores genera la siguiente, o da el torneo
+// por terminado si era la final.
+func handleAdvanceTournament(w http.ResponseWriter, r *http.Request, tournamentID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	roomID := query.Get("roomID")
+	winner := query.Get("winner")
+	if roomID == "" || winner == "" {
+		http.Error(w, "roomID and winner are required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	state.mu.Lock()
+	t, exists := state.tournaments[tournamentID]
+	if !exists {
+		state.mu.Unlock()
+		http.Error(w, "Tournament not found", http.StatusNotFound)
+		return
+	}
+	if t.State != TournamentRunning {
+		state.mu.Unlock()
+		http.Error(w, "Tournament is not running", http.StatusConflict)
+		return
+	}
+
+	round := t.Rounds[len(t.Rounds)-1]
+	k := slices.IndexFunc(round, func(m *BracketMatch) bool { return m.RoomID == roomID })
+	if k == -1 {
+		state.mu.Unlock()
+		http.Error(w, "Room is not in the current round", http.StatusNotFound)
+		return
+	}
+	match := round[k]
+	if match.Winner != "" {
+		state.mu.Unlock()
+		http.Error(w, "Match already reported", http.StatusConflict)
+		return
+	}
+	if winner != match.Players[0] && winner != match.Players[1] {
+		state.mu.Unlock()
+		http.Error(w, "Winner is not in this match", http.StatusBadRequest)
+		return
+	}
+
+	match.Winner = winner
+	t.UpdatedAt = now
+	// La sala puede haber terminado ya por /room/{id}/finish o haber caducado
+	if room, ok := state.rooms[roomID]; ok && room.State == RoomActive {
+		room.transition(RoomFinished, now)
+	}
+
+	var created []*MatchRecord
+	if !slices.ContainsFunc(round, func(m *BracketMatch) bool { return m.Winner == "" }) {
+		if len(round) == 1 {
+			t.State = TournamentFinished
+			t.Winner = winner
+		} else {
+			winners := make([]string, len(round))
+			for i, m := range round {
+				winners[i] = m.Winner
+			}
+			created = t.startRound(winners, now)
+		}
+	}
+	status, champion := t.State, t.Winner
+	state.mu.Unlock()
+
+	slog.InfoContext(r.Context(), "tournament match reported", "tournament_id", tournamentID, "room_id", roomID, "winner", winner)
+	recordMatchEnded(roomID, now)
+	recordTournamentRound(r.Context(), tournamentID, created)
+	if champion != "" {
+		slog.InfoContext(r.Context(), "tournament finished", "tournament_id", tournamentID, "winner", champion)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"tournamentID": tournamentID,
+		"state":        status,
+		"winner":       champion,
+	})
+}
+
+// handleBracket atiende GET /tournament/{id}/bracket con el torneo completo.
+func handleBracket(w http.ResponseWriter, r *http.Request, tournamentID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Serializamos bajo el lock: las partidas del cuadro se modifican en sitio
+	state.mu.RLock()
+	t, exists := state.tournaments[tournamentID]
+	var payload []byte
+	if exists {
+		payload, _ = json.Marshal(t)
+	}
+	state.mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Tournament not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(payload, '\n'))
+}
+
+// recordTournamentRound registra en el historial las salas de una ronda nueva.
+func recordTournamentRound(ctx context.Context, tournamentID string, created []*MatchRecord) {
+	for _, m := range created {
+		slog.InfoContext(ctx, "match created", "room_id", m.RoomID, "players", m.Players, "tournament_id", tournamentID)
+		matchesCreated.Inc()
+		recordMatchCreated(m.RoomID, m.Players, m.CreatedAt)
+	}
+}
+
+// expireTournaments olvida los torneos sin cambios desde hace más de tournamentTTL.
+// Requiere state.mu.
+func expireTournaments() {
+	now := time.Now()
+	for id, t := range state.tournaments {
+		if now.Sub(t.UpdatedAt) > tournamentTTL {
+			delete(state.tournaments, id)
+		}
+	}
+}
+
+// anonymizeTournaments sustituye playerID por anonID en los cuadros. Requiere state.mu.
+func anonymizeTournaments(playerID, anonID string) {
+	replace := func(id *string) {
+		if *id == playerID {
+			*id = anonID
+		}
+	}
+	for _, t := range state.tournaments {
+		for k := range t.Slots {
+			replace(&t.Slots[k])
+		}
+		for _, round := range t.Rounds {
+			for _, m := range round {
+				replace(&m.Players[0])
+				replace(&m.Players[1])
+				replace(&m.Winner)
+			}
+		}
+		replace(&t.Winner)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsWriteTimeout limita cuánto puede bloquear una escritura en un websocket.
+const wsWriteTimeout = 5 * time.Second
+
+// upgrader comprueba el origen con wsCheckOrigin, que main configura con la misma lista
+// de orígenes que cors.
+var upgrader = websocket.Upgrader{}
+
+// wsConn serializa las escrituras en un websocket, que no admite escritores concurrentes.
+type wsConn struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (c *wsConn) WriteJSON(v any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return c.conn.WriteJSON(v)
+}
+
+// wsMessage es el formato de los mensajes en ambos sentidos.
+type wsMessage struct {
+	Type    string   `json:"type"`
+	Players []string `json:"players,omitempty"`
+	// MatchQuality es un puntero para que una diferencia de 0 no se omita
+	MatchQuality   *int   `json:"matchQuality,omitempty"`
+	RoomID         string `json:"roomID,omitempty"`
+	OpponentName   string `json:"opponentName,omitempty"`
+	OpponentAvatar string `json:"opponentAvatar,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+// wsPush es un aviso de emparejamiento pendiente de enviar por websocket. Guarda el
+// socket y el mensaje tomados bajo state.mu, para enviarlo después sin el lock.
+type wsPush struct {
+	player      *Player
+	conn        *wsConn
+	msg         wsMessage
+	opponentIDs []string
+}
+
+// newWSPush prepara el aviso de emparejamiento de p por conn. Requiere state.mu.
+func newWSPush(p *Player, conn *wsConn, opponentIDs []string) *wsPush {
+	quality := p.MatchQuality
+	return &wsPush{
+		player: p,
+		conn:   conn,
+		msg: wsMessage{
+			Type:           "matched",
+			Players:        opponentIDs,
+			RoomID:         p.RoomID,
+			MatchQuality:   &quality,
+			OpponentName:   p.OpponentName,
+			OpponentAvatar: p.OpponentAvatar,
+		},
+		opponentIDs: opponentIDs,
+	}
+}
+
+// deliver envía el aviso y, como handleStatus, pasa al jugador a reconnecting.
+// Si la escritura falla, deja el aviso en OpponentIDs para que lo recoja /status.
+func (p *wsPush) deliver() {
+	if err := p.conn.WriteJSON(p.msg); err != nil {
+		p.player.OpponentIDs <- p.opponentIDs
+		return
+	}
+
+	state.mu.Lock()
+	markDelivered(p.player, p.opponentIDs)
+	state.mu.Unlock()
+}
+
+// handleWebSocket atiende /ws/{playerID}. Asocia el socket al jugador para que
+// matchPlayers le notifique directamente, y acepta los mensajes ping y cancel.
+func handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	playerID := r.URL.Path[len("/ws/"):]
+	if playerID == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	state.mu.RLock()
+	player, exists := state.players[playerID]
+	state.mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Player not found", http.StatusNotFound)
+		return
+	}
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade ya ha respondido al cliente
+	}
+	conn := &wsConn{conn: ws}
+	defer ws.Close()
+
+	// Un segundo /ws/ para el mismo jugador sustituye al anterior, que se cierra
+	state.mu.Lock()
+	previous := player.Conn
+	player.Conn = conn
+	state.mu.Unlock()
+	if previous != nil {
+		previous.conn.Close()
+	}
+
+	// Si el emparejamiento llegó antes de conectar, lo reenviamos ahora
+	select {
+	case opponentIDs := <-player.OpponentIDs:
+		state.mu.Lock()
+		push := newWSPush(player, conn, opponentIDs)
+		state.mu.Unlock()
+		push.deliver()
+	default:
+	}
+
+	// Al salir esperamos a la goroutine del aviso de cancelación, que aún lee state
+	done, stopped := make(chan struct{}), make(chan struct{})
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+	go func() {
+		defer close(stopped)
+		select {
+		case <-player.Cancelled:
+			reason := takeCancelReason(playerID)
+			conn.WriteJSON(wsMessage{Type: noticeStatus(reason), Reason: reason})
+			ws.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		var msg wsMessage
+		if err := ws.ReadJSON(&msg); err != nil {
+			break
+		}
+
+		switch msg.Type {
+		case "ping":
+			conn.WriteJSON(wsMessage{Type: "pong"})
+		case "cancel":
+			state.mu.Lock()
+			if state.players[playerID] == player {
+				removePlayer(playerID)
+			}
+			state.mu.Unlock()
+		}
+	}
+
+	// El socket se ha cerrado. Si otro /ws/ lo ha sustituido, el jugador es suyo. Si ya
+	// está emparejado lo dejamos: /status, /reconnect o un nuevo /ws/ recogen el aviso.
+	// Solo quien sigue en el pool sale de él.
+	state.mu.Lock()
+	if player.Conn == conn {
+		player.Conn = nil
+		if state.players[playerID] == player && !player.Matched {
+			removePlayer(playerID)
+		}
+	}
+	state.mu.Unlock()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialWS abre /ws/{id} contra srv.
+func dialWS(t *testing.T, srv *httptest.Server, id string) *websocket.Conn {
+	t.Helper()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/"+id, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+// eventually espera hasta un segundo a que cond se cumpla bajo state.mu.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		state.mu.RLock()
+		ok := cond()
+		state.mu.RUnlock()
+		if ok {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+// newWSServer sirve las rutas del servidor. Al terminar el test espera a que acaben los
+// handlers, que srv.Close no sigue tras el upgrade, para que no lleguen a ver el state
+// del test siguiente.
+func newWSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var handlers sync.WaitGroup
+	mux := routes(new(Config))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.Add(1)
+		defer handlers.Done()
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(func() {
+		srv.Close()
+		handlers.Wait()
+	})
+	return srv
+}
+
+func TestWebSocketMatchDelivered(t *testing.T) {
+	resetState(t)
+	cfg := defaultConfig()
+	srv := newWSServer(t)
+	now := time.Now()
+	a := addWaiting(t, "a_modo_ws", defaultELO, "", 0, now).Player
+	addWaiting(t, "b_modo_ws", defaultELO, "", 0, now)
+
+	ws := dialWS(t, srv, "a_modo_ws")
+	eventually(t, "socket registered", func() bool { return a.Conn != nil })
+
+	if matchRound(&cfg) == nil {
+		t.Fatal("matchRound created no room")
+	}
+	var msg wsMessage
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	if err := ws.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "matched" || len(msg.Players) != 1 || msg.Players[0] != "b_modo_ws" || msg.RoomID == "" {
+		t.Fatalf("got %+v, want a matched message with b_modo_ws", msg)
+	}
+	eventually(t, "player moved to reconnecting", func() bool { return state.reconnecting["a_modo_ws"] == a })
+}
+
+func TestWebSocketSecondConnectionReplacesFirst(t *testing.T) {
+	resetState(t)
+	srv := newWSServer(t)
+	p := addWaiting(t, "a_modo_ws", defaultELO, "", 0, time.Now()).Player
+
+	first := dialWS(t, srv, "a_modo_ws")
+	eventually(t, "first socket registered", func() bool { return p.Conn != nil })
+	var firstConn *wsConn
+	state.mu.RLock()
+	firstConn = p.Conn
+	state.mu.RUnlock()
+
+	dialWS(t, srv, "a_modo_ws")
+	eventually(t, "second socket registered", func() bool { return p.Conn != nil && p.Conn != firstConn })
+
+	// El servidor cierra el primer socket, y su limpieza no debe borrar al jugador
+	first.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := first.ReadMessage(); err == nil {
+		t.Fatal("first socket still open after a second /ws/")
+	}
+	time.Sleep(50 * time.Millisecond)
+	state.mu.RLock()
+	_, exists := state.players["a_modo_ws"]
+	state.mu.RUnlock()
+	if !exists {
+		t.Fatal("closing the replaced socket removed the player")
+	}
+}
+
+func TestWebSocketCloseKeepsMatchedPlayer(t *testing.T) {
+	resetState(t)
+	srv := newWSServer(t)
+	p := addWaiting(t, "a_modo_ws", defaultELO, "", 0, time.Now()).Player
+
+	ws := dialWS(t, srv, "a_modo_ws")
+	eventually(t, "socket registered", func() bool { return p.Conn != nil })
+
+	// Emparejado pero sin aviso entregado, como entre matchRound y deliver
+	state.mu.Lock()
+	removeFromPool(func(e *PoolEntry) bool { return e.Player == p })
+	p.Matched = true
+	p.RoomID = "r1"
+	state.mu.Unlock()
+
+	ws.Close()
+	eventually(t, "socket cleanup", func() bool { return p.Conn == nil })
+	state.mu.RLock()
+	_, exists := state.players["a_modo_ws"]
+	state.mu.RUnlock()
+	if !exists {
+		t.Fatal("closing the socket removed a matched player before delivery")
+	}
+}
+
+func TestWebSocketCloseRemovesWaitingPlayer(t *testing.T) {
+	resetState(t)
+	srv := newWSServer(t)
+	p := addWaiting(t, "a_modo_ws", defaultELO, "", 0, time.Now()).Player
+
+	ws := dialWS(t, srv, "a_modo_ws")
+	eventually(t, "socket registered", func() bool { return p.Conn != nil })
+
+	ws.Close()
+	eventually(t, "player removed", func() bool {
+		_, exists := state.players["a_modo_ws"]
+		return !exists && len(state.pool) == 0
+	})
+}